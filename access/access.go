@@ -0,0 +1,159 @@
+package access
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pulpfree/univsales-wrksht-pdf/cognito"
+	"github.com/pulpfree/univsales-wrksht-pdf/model"
+	log "github.com/sirupsen/logrus"
+)
+
+// Role constants, the values of the custom:role user attribute
+const (
+	RoleAdmin   = "admin"
+	RoleManager = "manager"
+	RoleRep     = "rep"
+)
+
+// AdminGroup is the Cognito group whose members see every quote, as with the admin role
+const AdminGroup = "admins"
+
+// ErrForbidden is returned for a quote outside the branches, or sales, the user may see
+var ErrForbidden = errors.New("Forbidden")
+
+// Check function
+// Returns ErrForbidden unless id may see the quote. Admins, services and the local tools see every quote,
+// managers the quotes of their branches, and reps only their own quotes within their branches.
+// A quote without a branch is seen by admins only, and a request without an identity sees none
+func Check(id *cognito.Identity, q *model.Quote) error {
+
+	if id == nil {
+		return fmt.Errorf("%w: no identity for quote %d", ErrForbidden, q.Number)
+	}
+	if Admin(id) || id.Caller == cognito.CallerService || id.Caller == cognito.CallerLocal {
+		return nil
+	}
+
+	if q.Branch == "" || !contains(id.Branches, q.Branch) {
+		return fmt.Errorf("%w: quote %d is outside the user's branches", ErrForbidden, q.Number)
+	}
+	if id.Role == RoleRep && q.SalesRepID != id.UserID {
+		return fmt.Errorf("%w: quote %d belongs to another sales rep", ErrForbidden, q.Number)
+	}
+
+	return nil
+}
+
+// CheckJob function
+// Returns ErrForbidden unless id may see the job, the user who queued it, or an admin, service or local tool
+func CheckJob(id *cognito.Identity, j *model.Job) error {
+
+	if id == nil {
+		return fmt.Errorf("%w: no identity for job %s", ErrForbidden, j.ID.Hex())
+	}
+	if Admin(id) || id.Caller == cognito.CallerService || id.Caller == cognito.CallerLocal {
+		return nil
+	}
+	if j.UserID == "" || j.UserID != id.UserID {
+		return fmt.Errorf("%w: job %s was queued by another user", ErrForbidden, j.ID.Hex())
+	}
+
+	return nil
+}
+
+// CheckAdmin function
+// Returns ErrForbidden unless id is an admin or a local tool, as for the retention endpoints
+func CheckAdmin(id *cognito.Identity) error {
+	if id == nil || (!Admin(id) && id.Caller != cognito.CallerLocal) {
+		return fmt.Errorf("%w: admins only", ErrForbidden)
+	}
+	return nil
+}
+
+// CheckRecipients function
+// Returns ErrForbidden unless id may email each address. Admins, managers, services and the local tools
+// may email any address, other users only the allowed addresses of the quote's customer and installers
+func CheckRecipients(id *cognito.Identity, to []string, allowed []string) error {
+
+	if id == nil {
+		return fmt.Errorf("%w: no identity for email", ErrForbidden)
+	}
+	if Admin(id) || id.Role == RoleManager || id.Caller == cognito.CallerService || id.Caller == cognito.CallerLocal {
+		return nil
+	}
+	for _, addr := range to {
+		if !containsFold(allowed, addr) {
+			return fmt.Errorf("%w: %s is not the customer or an installer", ErrForbidden, addr)
+		}
+	}
+
+	return nil
+}
+
+// Admin function
+// Reports whether the user bypasses the quote checks
+func Admin(id *cognito.Identity) bool {
+	return id.Role == RoleAdmin || contains(id.Groups, AdminGroup)
+}
+
+// Local function
+// Returns the identity of the local tools, the cli and the server run without auth, which see every quote
+func Local() *cognito.Identity {
+	return &cognito.Identity{Caller: cognito.CallerLocal, UserID: "local"}
+}
+
+// DB struct
+// Wraps a DBHandler so each fetched quote is checked against the identity.
+// The quote id lookups pass through, as the quotes they return are checked when fetched
+type DB struct {
+	model.DBHandler
+	id *cognito.Identity
+}
+
+// NewDB function
+// Returns db scoped to id. Without an identity every fetched quote is refused
+func NewDB(db model.DBHandler, id *cognito.Identity) model.DBHandler {
+	return &DB{DBHandler: db, id: id}
+}
+
+// FetchQuote method
+func (db *DB) FetchQuote(quoteID string) (*model.Quote, error) {
+
+	q, err := db.DBHandler.FetchQuote(quoteID)
+	if err != nil {
+		return q, err
+	}
+	if err := Check(db.id, q); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// WithLogger method
+func (db *DB) WithLogger(l *log.Entry) model.DBHandler {
+	return &DB{DBHandler: db.DBHandler.WithLogger(l), id: db.id}
+}
+
+// ================================ Helper Functions
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// containsFold compares email addresses, which are matched regardless of case
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,176 @@
+package access
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pulpfree/univsales-wrksht-pdf/cognito"
+	"github.com/pulpfree/univsales-wrksht-pdf/model"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AccessSuite struct
+type AccessSuite struct {
+	suite.Suite
+}
+
+// TestCheck method
+func (suite *AccessSuite) TestCheck() {
+
+	q := &model.Quote{Branch: "hamilton", Number: 1042, SalesRepID: "rep-1"}
+
+	tests := []struct {
+		name    string
+		id      *cognito.Identity
+		q       *model.Quote
+		allowed bool
+	}{
+		{"no identity", nil, q, false},
+		{"local", Local(), q, true},
+		{"admin role", &cognito.Identity{Role: RoleAdmin, UserID: "a"}, q, true},
+		{"admin group", &cognito.Identity{Groups: []string{"sales", AdminGroup}, UserID: "a"}, q, true},
+		{"service", &cognito.Identity{Caller: cognito.CallerService, Name: "scheduler", UserID: "svc"}, q, true},
+		{"manager in branch", &cognito.Identity{Branches: []string{"burlington", "hamilton"}, Role: RoleManager, UserID: "m"}, q, true},
+		{"manager outside branch", &cognito.Identity{Branches: []string{"burlington"}, Role: RoleManager, UserID: "m"}, q, false},
+		{"no role in branch", &cognito.Identity{Branches: []string{"hamilton"}, UserID: "u"}, q, true},
+		{"no branches", &cognito.Identity{Role: RoleManager, UserID: "m"}, q, false},
+		{"own rep", &cognito.Identity{Branches: []string{"hamilton"}, Role: RoleRep, UserID: "rep-1"}, q, true},
+		{"other rep", &cognito.Identity{Branches: []string{"hamilton"}, Role: RoleRep, UserID: "rep-2"}, q, false},
+		{"rep outside branch", &cognito.Identity{Branches: []string{"burlington"}, Role: RoleRep, UserID: "rep-1"}, q, false},
+		{"quote without branch", &cognito.Identity{Branches: []string{"hamilton"}, Role: RoleManager, UserID: "m"}, &model.Quote{Number: 7}, false},
+		{"admin quote without branch", &cognito.Identity{Role: RoleAdmin, UserID: "a"}, &model.Quote{Number: 7}, true},
+	}
+
+	for _, tt := range tests {
+		err := Check(tt.id, tt.q)
+		if tt.allowed {
+			suite.NoError(err, tt.name)
+		} else {
+			suite.True(errors.Is(err, ErrForbidden), "%s: %v", tt.name, err)
+		}
+	}
+}
+
+// TestCheckJob method
+func (suite *AccessSuite) TestCheckJob() {
+
+	j := &model.Job{ID: primitive.NewObjectID(), UserID: "rep-1"}
+
+	tests := []struct {
+		name    string
+		id      *cognito.Identity
+		j       *model.Job
+		allowed bool
+	}{
+		{"no identity", nil, j, false},
+		{"owner", &cognito.Identity{Role: RoleRep, UserID: "rep-1"}, j, true},
+		{"other user", &cognito.Identity{Branches: []string{"hamilton"}, Role: RoleManager, UserID: "m"}, j, false},
+		{"admin", &cognito.Identity{Groups: []string{AdminGroup}, UserID: "a"}, j, true},
+		{"service", &cognito.Identity{Caller: cognito.CallerService, UserID: "svc"}, j, true},
+		{"local", Local(), j, true},
+		{"job without owner", &cognito.Identity{UserID: ""}, &model.Job{ID: primitive.NewObjectID()}, false},
+	}
+
+	for _, tt := range tests {
+		err := CheckJob(tt.id, tt.j)
+		if tt.allowed {
+			suite.NoError(err, tt.name)
+		} else {
+			suite.True(errors.Is(err, ErrForbidden), "%s: %v", tt.name, err)
+		}
+	}
+}
+
+// TestCheckRecipients method
+func (suite *AccessSuite) TestCheckRecipients() {
+
+	allowed := []string{"jo@example.com", "crew@example.com"}
+	rep := &cognito.Identity{Branches: []string{"hamilton"}, Role: RoleRep, UserID: "rep-1"}
+
+	tests := []struct {
+		name    string
+		id      *cognito.Identity
+		to      []string
+		allowed bool
+	}{
+		{"no identity", nil, []string{"jo@example.com"}, false},
+		{"rep to customer and installers", rep, []string{"Jo@Example.com", "crew@example.com"}, true},
+		{"rep to other address", rep, []string{"jo@example.com", "someone@example.com"}, false},
+		{"manager to other address", &cognito.Identity{Branches: []string{"hamilton"}, Role: RoleManager, UserID: "m"}, []string{"someone@example.com"}, true},
+		{"admin to other address", &cognito.Identity{Groups: []string{AdminGroup}, UserID: "a"}, []string{"someone@example.com"}, true},
+		{"service to other address", &cognito.Identity{Caller: cognito.CallerService, UserID: "svc"}, []string{"someone@example.com"}, true},
+		{"local to other address", Local(), []string{"someone@example.com"}, true},
+	}
+
+	for _, tt := range tests {
+		err := CheckRecipients(tt.id, tt.to, allowed)
+		if tt.allowed {
+			suite.NoError(err, tt.name)
+		} else {
+			suite.True(errors.Is(err, ErrForbidden), "%s: %v", tt.name, err)
+		}
+	}
+}
+
+// TestCheckAdmin method
+func (suite *AccessSuite) TestCheckAdmin() {
+
+	suite.NoError(CheckAdmin(&cognito.Identity{Groups: []string{AdminGroup}, UserID: "a"}))
+	suite.NoError(CheckAdmin(Local()))
+	for _, id := range []*cognito.Identity{nil, {Role: RoleManager, UserID: "m"}, {Caller: cognito.CallerService, UserID: "svc"}} {
+		suite.True(errors.Is(CheckAdmin(id), ErrForbidden))
+	}
+}
+
+// TestDB method
+func (suite *AccessSuite) TestDB() {
+
+	db := &quoteDB{q: &model.Quote{Branch: "hamilton", Number: 1042}}
+
+	_, err := NewDB(db, nil).FetchQuote("q1")
+	suite.True(errors.Is(err, ErrForbidden))
+
+	scoped := NewDB(db, &cognito.Identity{Branches: []string{"hamilton"}, UserID: "u"})
+	q, err := scoped.FetchQuote("q1")
+	suite.NoError(err)
+	suite.Equal(1042, q.Number)
+
+	// the scope survives a logger change
+	scoped = NewDB(db, &cognito.Identity{Branches: []string{"burlington"}, UserID: "u"}).WithLogger(log.NewEntry(log.StandardLogger()))
+	q, err = scoped.FetchQuote("q1")
+	suite.True(errors.Is(err, ErrForbidden))
+	suite.Nil(q)
+
+	// fetch errors are returned as they are
+	db.err = model.ErrQuoteNotFound
+	_, err = scoped.FetchQuote("q1")
+	suite.True(errors.Is(err, model.ErrQuoteNotFound))
+}
+
+// ================================ Helper Methods
+
+type quoteDB struct {
+	err error
+	q   *model.Quote
+}
+
+func (db *quoteDB) Close() {}
+
+func (db *quoteDB) FetchQuote(string) (*model.Quote, error) { return db.q, db.err }
+
+func (db *quoteDB) FetchQuoteIDByNumber(int) (string, error) { return "", nil }
+
+func (db *quoteDB) FetchQuoteIDs(start, end time.Time) ([]string, error) { return nil, nil }
+
+func (db *quoteDB) Ping(ctx context.Context) error { return nil }
+
+func (db *quoteDB) WithLogger(*log.Entry) model.DBHandler { return db }
+
+// TestAccessSuite function
+func TestAccessSuite(t *testing.T) {
+	suite.Run(t, new(AccessSuite))
+}
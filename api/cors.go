@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/pulpfree/univsales-wrksht-pdf/config"
+)
+
+// CORS header values
+const (
+	corsAllowHeaders  = "Authorization,Content-Type,X-Amz-Date,X-Amz-Security-Token,X-Api-Key"
+	corsAllowMethods  = "GET,OPTIONS,POST"
+	corsExposeHeaders = "Content-Disposition"
+	corsMaxAge        = "600"
+)
+
+// localOrigins are allowed on any port in the dev and test stages
+var localOrigins = []string{"http://localhost:*", "http://127.0.0.1:*"}
+
+// HandlerFunc type
+type HandlerFunc func(context.Context, events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+
+// CORS struct
+// Adds the CORS headers to every response, including error responses, and answers preflight requests.
+// Origins are matched exactly or with a leading wildcard subdomain, such as https://*.example.com,
+// and a port of * matches any port. An origin of * allows every origin
+type CORS struct {
+	origins []string
+}
+
+// NewCORS function
+func NewCORS(cfg *config.Config) *CORS {
+
+	origins := append([]string{}, cfg.CORSOrigins...)
+	if stage := cfg.GetStageEnv(); stage == config.DevEnv || stage == config.TestEnv {
+		origins = append(origins, localOrigins...)
+	}
+
+	return &CORS{origins: origins}
+}
+
+// Wrap method
+func (c *CORS) Wrap(next HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+
+		origin := header(req, "Origin")
+
+		if req.HTTPMethod == http.MethodOptions {
+			res := events.APIGatewayProxyResponse{Headers: map[string]string{}, StatusCode: http.StatusNoContent}
+			if c.allowed(origin) {
+				res.Headers["Access-Control-Allow-Headers"] = corsAllowHeaders
+				res.Headers["Access-Control-Allow-Methods"] = corsAllowMethods
+				res.Headers["Access-Control-Max-Age"] = corsMaxAge
+			}
+			c.setHeaders(res.Headers, origin)
+			return res, nil
+		}
+
+		res, err := next(ctx, req)
+		if res.Headers == nil {
+			res.Headers = map[string]string{}
+		}
+		c.setHeaders(res.Headers, origin)
+
+		return res, err
+	}
+}
+
+// ================================ Helper Methods
+
+func (c *CORS) setHeaders(hdrs map[string]string, origin string) {
+
+	// responses differ by origin, so caches must not share them across origins
+	hdrs["Vary"] = "Origin"
+	if !c.allowed(origin) {
+		return
+	}
+
+	hdrs["Access-Control-Allow-Origin"] = origin
+	hdrs["Access-Control-Expose-Headers"] = corsExposeHeaders
+}
+
+func (c *CORS) allowed(origin string) bool {
+
+	if origin == "" {
+		return false
+	}
+	for _, o := range c.origins {
+		if o == "*" || matchOrigin(o, origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchOrigin matches the scheme, host and port of origin against pattern
+func matchOrigin(pattern, origin string) bool {
+
+	pScheme, pHost, pPort := splitOrigin(pattern)
+	oScheme, oHost, oPort := splitOrigin(origin)
+
+	if !strings.EqualFold(pScheme, oScheme) {
+		return false
+	}
+	if pPort != "*" && pPort != oPort {
+		return false
+	}
+	if strings.HasPrefix(pHost, "*.") {
+		return strings.HasSuffix(strings.ToLower(oHost), strings.ToLower(pHost[1:]))
+	}
+
+	return strings.EqualFold(pHost, oHost)
+}
+
+func splitOrigin(origin string) (scheme, host, port string) {
+
+	i := strings.Index(origin, "://")
+	if i < 0 {
+		return "", origin, ""
+	}
+	scheme, host = origin[:i], strings.TrimSuffix(origin[i+3:], "/")
+
+	if j := strings.LastIndex(host, ":"); j >= 0 {
+		host, port = host[:j], host[j+1:]
+	}
+
+	return scheme, host, port
+}
+
+// header returns the request header, api gateway passes header names as the client sent them
+func header(req events.APIGatewayProxyRequest, name string) string {
+	for k, v := range req.Headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
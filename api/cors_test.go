@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/suite"
+)
+
+// CORSSuite struct
+type CORSSuite struct {
+	suite.Suite
+	c *CORS
+}
+
+// SetupTest method
+func (suite *CORSSuite) SetupTest() {
+	suite.c = &CORS{origins: append([]string{"https://universalwindows.ca", "https://*.universalwindows.ca"}, localOrigins...)}
+}
+
+// TestAllowed method
+func (suite *CORSSuite) TestAllowed() {
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://universalwindows.ca", true},
+		{"https://office.universalwindows.ca", true},
+		{"https://a.b.universalwindows.ca", true},
+		{"https://OFFICE.universalwindows.ca", true},
+		{"http://office.universalwindows.ca", false},
+		{"https://evil-universalwindows.ca", false},
+		{"https://universalwindows.ca.evil.com", false},
+		{"https://office.universalwindows.ca:8443", false},
+		{"http://localhost:3000", true},
+		{"http://127.0.0.1:8080", true},
+		{"https://localhost:3000", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		suite.Equal(tt.allowed, suite.c.allowed(tt.origin), tt.origin)
+	}
+
+	suite.True((&CORS{origins: []string{"*"}}).allowed("https://example.com"))
+}
+
+// TestWrap method
+func (suite *CORSSuite) TestWrap() {
+
+	next := func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		return ProblemResponse(NewError(http.StatusNotFound, CodeJobNotFound, errors.New("job not found")), map[string]string{}), nil
+	}
+	h := suite.c.Wrap(next)
+
+	res, err := h(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: "GET", Headers: map[string]string{"origin": "https://office.universalwindows.ca"}})
+	suite.NoError(err)
+	suite.Equal(http.StatusNotFound, res.StatusCode)
+	suite.Equal("https://office.universalwindows.ca", res.Headers["Access-Control-Allow-Origin"])
+	suite.Equal("Origin", res.Headers["Vary"])
+
+	res, _ = h(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: "GET", Headers: map[string]string{"Origin": "https://example.com"}})
+	suite.Empty(res.Headers["Access-Control-Allow-Origin"])
+
+	res, _ = h(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: "OPTIONS", Headers: map[string]string{"Origin": "http://localhost:3000"}})
+	suite.Equal(http.StatusNoContent, res.StatusCode)
+	suite.Equal("http://localhost:3000", res.Headers["Access-Control-Allow-Origin"])
+	suite.Equal(corsAllowMethods, res.Headers["Access-Control-Allow-Methods"])
+}
+
+// TestCORSSuite function
+func TestCORSSuite(t *testing.T) {
+	suite.Run(t, new(CORSSuite))
+}
@@ -0,0 +1,445 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	pres "github.com/pulpfree/lambda-go-proxy-response"
+	"github.com/pulpfree/univsales-wrksht-pdf/access"
+	"github.com/pulpfree/univsales-wrksht-pdf/cognito"
+	"github.com/pulpfree/univsales-wrksht-pdf/config"
+	"github.com/pulpfree/univsales-wrksht-pdf/health"
+	"github.com/pulpfree/univsales-wrksht-pdf/logger"
+	"github.com/pulpfree/univsales-wrksht-pdf/model"
+	"github.com/pulpfree/univsales-wrksht-pdf/model/mongo"
+	"github.com/pulpfree/univsales-wrksht-pdf/pdf"
+	"github.com/pulpfree/univsales-wrksht-pdf/queue"
+	"github.com/pulpfree/univsales-wrksht-pdf/service"
+	"github.com/pulpfree/univsales-wrksht-pdf/storage"
+	"github.com/pulpfree/univsales-wrksht-pdf/tracing"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+)
+
+// Resource constants, matching the api paths in template.yml
+const (
+	BatchResource  = "/batch"
+	HealthResource = "/health"
+	JobResource    = "/jobs/{id}"
+	PruneResource  = "/worksheets/{number}/prune"
+	RootResource   = "/"
+	WrkshtResource = "/worksheets/{number}"
+)
+
+// healthTimeout limits each dependency check of the health endpoint
+const healthTimeout = 3 * time.Second
+
+// Handler struct
+// Serves the api gateway proxy requests, database connections are opened on first use and shared
+type Handler struct {
+	cfg   *config.Config
+	cors  *CORS
+	db    model.DBHandler
+	jobs  model.JobStore
+	mu    sync.Mutex
+	queue queue.Queue
+}
+
+// NewHandler function
+func NewHandler(cfg *config.Config) *Handler {
+	return &Handler{
+		cfg:  cfg,
+		cors: NewCORS(cfg),
+	}
+}
+
+// HandleRequest method
+func (h *Handler) HandleRequest(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return h.cors.Wrap(h.traced(h.handle))(ctx, req)
+}
+
+// ================================ Helper Methods
+
+// traced runs next in a span for the request, the request logger carries the span to the other layers
+func (h *Handler) traced(next HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+
+		ctx, span := tracing.Start(ctx, req.HTTPMethod+" "+req.Resource,
+			semconv.HTTPMethodKey.String(req.HTTPMethod),
+			semconv.HTTPRouteKey.String(req.Resource),
+			attribute.String(logger.FieldRequestID, req.RequestContext.RequestID),
+		)
+		res, err := next(ctx, req)
+		span.SetAttributes(semconv.HTTPStatusCodeKey.Int(res.StatusCode))
+		if err == nil && res.StatusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(res.StatusCode))
+		}
+		tracing.End(span, err)
+
+		return res, err
+	}
+}
+
+func (h *Handler) handle(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+
+	hdrs := make(map[string]string)
+	hdrs["Content-Type"] = "application/json"
+
+	t := time.Now()
+	l := logger.ForRequest(req, h.cfg).WithContext(ctx)
+
+	if req.HTTPMethod == "GET" && req.Resource == HealthResource {
+		return h.health(ctx, hdrs, t, l), nil
+	}
+
+	if req.HTTPMethod == "GET" && req.Resource == JobResource {
+		return h.jobStatus(req, hdrs, t, l), nil
+	}
+
+	if req.HTTPMethod == "GET" && req.Resource == WrkshtResource {
+		return h.worksheet(req, hdrs, t, l), nil
+	}
+
+	if req.HTTPMethod == "POST" && req.Resource == PruneResource {
+		return h.prune(req, hdrs, t, l), nil
+	}
+
+	// If this is a ping test, intercept and return
+	if req.HTTPMethod == "GET" {
+		l.Info("Ping test in handleRequest")
+		return pres.ProxyRes(pres.Response{
+			Code:      200,
+			Data:      "pong",
+			Status:    "success",
+			Timestamp: t.Unix(),
+		}, hdrs, nil), nil
+	}
+
+	if req.Resource == BatchResource {
+		return h.batch(req, hdrs, t, l), nil
+	}
+
+	r, err := DecodeRequest(req.Body)
+	if err != nil {
+		return problemResponse(err, hdrs, l), nil
+	}
+	r.Identity = cognito.IdentityFromContext(req.RequestContext.Authorizer)
+	r.Principal = principal(req)
+	l = l.WithField(logger.FieldQuoteID, r.QuoteID)
+
+	if r.Async {
+		return h.enqueue(service.JobWorksheet, r, r.Identity, hdrs, t, l), nil
+	}
+
+	svc, err := h.service(l)
+	if err != nil {
+		return problemResponse(err, hdrs, l), nil
+	}
+
+	// Callers without S3 access get the file itself in the response body
+	if r.Delivery == pdf.DeliveryInline || acceptsPDF(req) {
+		p, err := svc.Render(r)
+		if err != nil {
+			return problemResponse(err, hdrs, l), nil
+		}
+		body, err := p.Bytes()
+		if err != nil {
+			return problemResponse(&service.Error{Op: service.OpRender, Err: err}, hdrs, l), nil
+		}
+		l.Infof("Successfully created inline PDF: %s", p.FileName())
+
+		hdrs["Content-Type"] = "application/pdf"
+		hdrs["Content-Disposition"] = fmt.Sprintf("attachment; filename=\"%s\"", p.FileName())
+		return events.APIGatewayProxyResponse{
+			Body:            base64.StdEncoding.EncodeToString(body),
+			Headers:         hdrs,
+			IsBase64Encoded: true,
+			StatusCode:      200,
+		}, nil
+	}
+
+	file, err := svc.Create(r)
+	if err != nil {
+		return problemResponse(err, hdrs, l), nil
+	}
+
+	code := 201
+	if file.Reused {
+		code = 200
+	}
+
+	return pres.ProxyRes(pres.Response{
+		Code:      code,
+		Data:      file,
+		Status:    "success",
+		Timestamp: t.Unix(),
+	}, hdrs, nil), nil
+}
+
+// service returns a service using the shared database connection, logging to l
+func (h *Handler) service(l *log.Entry) (*service.Service, error) {
+
+	db, err := h.database()
+	if err != nil {
+		return nil, err
+	}
+
+	return service.New(h.cfg, db).WithLogger(l), nil
+}
+
+// database returns the shared database connection, connecting on first use.
+// The connect runs without the lock, so a slow connect does not hold up the other requests
+func (h *Handler) database() (model.DBHandler, error) {
+
+	h.mu.Lock()
+	db := h.db
+	h.mu.Unlock()
+	if db != nil {
+		return db, nil
+	}
+
+	db, err := mongo.NewDB(h.cfg.GetMongoConnectURL(), h.cfg.DBName)
+	if err != nil {
+		return nil, NewError(http.StatusServiceUnavailable, CodeUnavailable, err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// another request connected first, keep its connection
+	if h.db != nil {
+		db.Close()
+		return h.db, nil
+	}
+	h.db = db
+
+	return db, nil
+}
+
+// jobService returns a service with the shared job store and queue
+// the queue consumer only needs the quote database once a local job runs
+func (h *Handler) jobService(l *log.Entry) (*service.Service, error) {
+
+	svc, err := h.service(l)
+	if err != nil {
+		return nil, err
+	}
+
+	h.mu.Lock()
+	jobs, q := h.jobs, h.queue
+	h.mu.Unlock()
+	if jobs != nil {
+		return svc.UseJobs(jobs, q), nil
+	}
+
+	// connected without the lock, as for the database
+	jobs, err = mongo.NewJobStore(h.cfg.GetMongoConnectURL(), h.cfg.DBName)
+	if err != nil {
+		return nil, NewError(http.StatusServiceUnavailable, CodeUnavailable, err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.jobs != nil {
+		jobs.Close()
+	} else {
+		h.jobs = jobs
+		h.queue = queue.New(h.cfg, service.New(h.cfg, h.db).UseJobs(jobs, nil).RunJob)
+	}
+
+	return svc.UseJobs(h.jobs, h.queue), nil
+}
+
+// batch renders the requested quotes, one bad quote is reported in the result rather than failing the batch
+func (h *Handler) batch(req events.APIGatewayProxyRequest, hdrs map[string]string, t time.Time, l *log.Entry) events.APIGatewayProxyResponse {
+
+	br, err := DecodeBatchRequest(req.Body)
+	if err != nil {
+		return problemResponse(err, hdrs, l)
+	}
+	br.Identity = cognito.IdentityFromContext(req.RequestContext.Authorizer)
+
+	if br.Async {
+		return h.enqueue(service.JobBatch, br, br.Identity, hdrs, t, l)
+	}
+
+	svc, err := h.service(l)
+	if err != nil {
+		return problemResponse(err, hdrs, l)
+	}
+
+	res, err := svc.Batch(br)
+	if err != nil {
+		apiErr := FromError(err)
+		if res != nil {
+			for _, item := range res.Quotes {
+				if !item.Success {
+					apiErr.Fields = append(apiErr.Fields, &FieldError{Field: item.QuoteID, Message: item.Error})
+				}
+			}
+		}
+		return problemResponse(apiErr, hdrs, l)
+	}
+
+	return pres.ProxyRes(pres.Response{
+		Code:      201,
+		Data:      res,
+		Status:    "success",
+		Timestamp: t.Unix(),
+	}, hdrs, nil)
+}
+
+// enqueue stores the request as a job and responds with its id for polling
+func (h *Handler) enqueue(kind string, r interface{}, id *cognito.Identity, hdrs map[string]string, t time.Time, l *log.Entry) events.APIGatewayProxyResponse {
+
+	svc, err := h.jobService(l)
+	if err != nil {
+		return problemResponse(err, hdrs, l)
+	}
+
+	j, err := svc.Enqueue(kind, r, id)
+	if err != nil {
+		return problemResponse(err, hdrs, l)
+	}
+
+	return pres.ProxyRes(pres.Response{
+		Code:      202,
+		Data:      j,
+		Status:    "success",
+		Timestamp: t.Unix(),
+	}, hdrs, nil)
+}
+
+// jobStatus responds with the current state of an asynchronous job
+func (h *Handler) jobStatus(req events.APIGatewayProxyRequest, hdrs map[string]string, t time.Time, l *log.Entry) events.APIGatewayProxyResponse {
+
+	svc, err := h.jobService(l)
+	if err != nil {
+		return problemResponse(err, hdrs, l)
+	}
+
+	j, err := svc.Job(req.PathParameters["id"], cognito.IdentityFromContext(req.RequestContext.Authorizer))
+	if err != nil {
+		return problemResponse(err, hdrs, l)
+	}
+
+	return pres.ProxyRes(pres.Response{
+		Code:      200,
+		Data:      j,
+		Status:    "success",
+		Timestamp: t.Unix(),
+	}, hdrs, nil)
+}
+
+// worksheet responds with the latest stored worksheet of a quote the caller may see
+func (h *Handler) worksheet(req events.APIGatewayProxyRequest, hdrs map[string]string, t time.Time, l *log.Entry) events.APIGatewayProxyResponse {
+
+	number, err := quoteNumber(req)
+	if err != nil {
+		return problemResponse(err, hdrs, l)
+	}
+
+	svc, err := h.service(l)
+	if err != nil {
+		return problemResponse(err, hdrs, l)
+	}
+
+	file, err := svc.Worksheet(number, cognito.IdentityFromContext(req.RequestContext.Authorizer))
+	if err != nil {
+		return problemResponse(err, hdrs, l)
+	}
+
+	return pres.ProxyRes(pres.Response{
+		Code:      200,
+		Data:      file,
+		Status:    "success",
+		Timestamp: t.Unix(),
+	}, hdrs, nil)
+}
+
+// prune applies the retention policy to the worksheets of a quote, for admins only
+func (h *Handler) prune(req events.APIGatewayProxyRequest, hdrs map[string]string, t time.Time, l *log.Entry) events.APIGatewayProxyResponse {
+
+	number, err := quoteNumber(req)
+	if err != nil {
+		return problemResponse(err, hdrs, l)
+	}
+	if err := access.CheckAdmin(cognito.IdentityFromContext(req.RequestContext.Authorizer)); err != nil {
+		return problemResponse(err, hdrs, l)
+	}
+
+	deleted, err := service.New(h.cfg, nil).WithLogger(l).Prune(number)
+	if err != nil {
+		return problemResponse(err, hdrs, l)
+	}
+	l.Infof("Pruned %d versions of worksheet %d", len(deleted), number)
+
+	return pres.ProxyRes(pres.Response{
+		Code:      200,
+		Data:      map[string][]string{"deleted": deleted},
+		Status:    "success",
+		Timestamp: t.Unix(),
+	}, hdrs, nil)
+}
+
+// health checks the dependencies, responding with 503 when one is down.
+// The checks stop when the request is cancelled
+func (h *Handler) health(ctx context.Context, hdrs map[string]string, t time.Time, l *log.Entry) events.APIGatewayProxyResponse {
+
+	rep := health.Run(ctx, []*health.Check{
+		health.Config(h.cfg),
+		health.Database(h.database),
+		health.Storage(storage.New(h.cfg)),
+		health.Logo(h.cfg.LogoURI),
+	}, healthTimeout)
+
+	res := pres.Response{
+		Code:      http.StatusOK,
+		Data:      rep,
+		Status:    "success",
+		Timestamp: t.Unix(),
+	}
+	// the route has no authorizer, so the dependency errors are logged rather than returned
+	for _, c := range rep.Checks {
+		if c.Status != health.StatusOK {
+			l.Errorf("Health check %s failed: %s", c.Name, c.Error)
+			c.Error = ""
+		}
+	}
+	if rep.Status == health.StatusDown {
+		res.Code = http.StatusServiceUnavailable
+		res.Status = "fail"
+	}
+
+	return pres.ProxyRes(res, hdrs, nil)
+}
+
+// acceptsPDF reports whether the client asked for the raw pdf with the Accept header
+func acceptsPDF(req events.APIGatewayProxyRequest) bool {
+	return strings.Contains(header(req, "Accept"), "application/pdf")
+}
+
+// quoteNumber returns the quote number path parameter
+func quoteNumber(req events.APIGatewayProxyRequest) (int, error) {
+	number, err := strconv.Atoi(req.PathParameters["number"])
+	if err != nil || number < 1 {
+		return 0, NewError(http.StatusBadRequest, CodeBadRequest, fmt.Errorf("Invalid quote number: %q", req.PathParameters["number"]))
+	}
+	return number, nil
+}
+
+// principal returns the caller id set by the authorizer
+func principal(req events.APIGatewayProxyRequest) string {
+	id, _ := req.RequestContext.Authorizer["principalId"].(string)
+	return id
+}
@@ -0,0 +1,241 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/pulpfree/univsales-wrksht-pdf/cognito"
+	"github.com/pulpfree/univsales-wrksht-pdf/config"
+	"github.com/pulpfree/univsales-wrksht-pdf/model"
+	"github.com/pulpfree/univsales-wrksht-pdf/service"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// HandlerSuite struct
+// Covers the routes that answer before any database is needed, and the job routes with stub stores
+type HandlerSuite struct {
+	suite.Suite
+	dir string
+	h   *Handler
+}
+
+// SetupTest method
+func (suite *HandlerSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "api")
+	suite.NoError(err)
+	suite.dir = dir
+
+	cfg := &config.Config{}
+	cfg.OutputDir = dir
+	cfg.Stage = config.TestEnv
+	suite.h = NewHandler(cfg)
+}
+
+// TearDownTest method
+func (suite *HandlerSuite) TearDownTest() {
+	os.RemoveAll(suite.dir)
+}
+
+// TestWorksheetRoutes method
+func (suite *HandlerSuite) TestWorksheetRoutes() {
+
+	manager := (&cognito.Identity{Branches: []string{"hamilton"}, Caller: cognito.CallerUser, Role: "manager", UserID: "m"}).Context()
+
+	tests := []struct {
+		name   string
+		method string
+		res    string
+		number string
+		ctx    map[string]interface{}
+		status int
+		code   string
+	}{
+		{"bad number", "GET", WrkshtResource, "abc", manager, http.StatusBadRequest, CodeBadRequest},
+		{"zero number", "GET", WrkshtResource, "0", manager, http.StatusBadRequest, CodeBadRequest},
+		{"prune by manager", "POST", PruneResource, "1042", manager, http.StatusForbidden, CodeForbidden},
+		{"prune without identity", "POST", PruneResource, "1042", nil, http.StatusForbidden, CodeForbidden},
+	}
+
+	for _, tt := range tests {
+		res, err := suite.h.HandleRequest(context.Background(), events.APIGatewayProxyRequest{
+			HTTPMethod:     tt.method,
+			PathParameters: map[string]string{"number": tt.number},
+			RequestContext: events.APIGatewayProxyRequestContext{Authorizer: tt.ctx},
+			Resource:       tt.res,
+		})
+		suite.NoError(err)
+		suite.Equal(tt.status, res.StatusCode, tt.name)
+
+		p := &Problem{}
+		suite.NoError(json.Unmarshal([]byte(res.Body), p), tt.name)
+		suite.Equal(tt.code, p.Code, tt.name)
+	}
+}
+
+// TestHealthCancelled method
+// The checks end with the request rather than running on to their timeout
+func (suite *HandlerSuite) TestHealthCancelled() {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	t := time.Now()
+	res, err := suite.h.HandleRequest(ctx, events.APIGatewayProxyRequest{HTTPMethod: "GET", Resource: HealthResource})
+	suite.NoError(err)
+	suite.Equal(http.StatusServiceUnavailable, res.StatusCode)
+	suite.True(time.Since(t) < healthTimeout)
+}
+
+// TestJobRoutes method
+// Async requests are queued as jobs of the caller, which only the caller may poll
+func (suite *HandlerSuite) TestJobRoutes() {
+
+	jobs := &memJobs{jobs: map[string]*model.Job{}}
+	q := &memQueue{}
+	suite.h.db = noDB{}
+	suite.h.jobs = jobs
+	suite.h.queue = q
+
+	rep := (&cognito.Identity{Branches: []string{"hamilton"}, Caller: cognito.CallerUser, Role: "rep", UserID: "rep-1"}).Context()
+	other := (&cognito.Identity{Branches: []string{"hamilton"}, Caller: cognito.CallerUser, Role: "rep", UserID: "rep-2"}).Context()
+
+	tests := []struct {
+		name string
+		res  string
+		body string
+		kind string
+	}{
+		{"worksheet", RootResource, `{"quoteID":"` + quoteID + `","async":true}`, service.JobWorksheet},
+		{"batch", BatchResource, `{"quoteIDs":["` + quoteID + `"],"async":true}`, service.JobBatch},
+	}
+
+	for _, tt := range tests {
+		res, err := suite.h.HandleRequest(context.Background(), events.APIGatewayProxyRequest{
+			Body:           tt.body,
+			HTTPMethod:     "POST",
+			RequestContext: events.APIGatewayProxyRequestContext{Authorizer: rep},
+			Resource:       tt.res,
+		})
+		suite.NoError(err)
+		suite.Equal(http.StatusAccepted, res.StatusCode, tt.name)
+
+		j := suite.jobData(res.Body)
+		suite.Equal(tt.kind, j.Kind, tt.name)
+		suite.Equal(model.JobQueued, j.Status, tt.name)
+		suite.Equal("rep-1", jobs.jobs[j.ID.Hex()].UserID, tt.name)
+		suite.Equal(j.ID.Hex(), q.sent[len(q.sent)-1], tt.name)
+	}
+
+	jobID := q.sent[0]
+	poll := func(id string, ctx map[string]interface{}) events.APIGatewayProxyResponse {
+		res, err := suite.h.HandleRequest(context.Background(), events.APIGatewayProxyRequest{
+			HTTPMethod:     "GET",
+			PathParameters: map[string]string{"id": id},
+			RequestContext: events.APIGatewayProxyRequestContext{Authorizer: ctx},
+			Resource:       JobResource,
+		})
+		suite.NoError(err)
+		return res
+	}
+
+	res := poll(jobID, rep)
+	suite.Equal(http.StatusOK, res.StatusCode)
+	suite.Equal(jobID, suite.jobData(res.Body).ID.Hex())
+
+	suite.problem(poll(jobID, other), http.StatusForbidden, CodeForbidden)
+	suite.problem(poll(jobID, nil), http.StatusForbidden, CodeForbidden)
+	suite.problem(poll(primitive.NewObjectID().Hex(), rep), http.StatusNotFound, CodeJobNotFound)
+
+	// a job the queue rejects is reported as a queue failure
+	q.err = errors.New("queue full")
+	res, err := suite.h.HandleRequest(context.Background(), events.APIGatewayProxyRequest{
+		Body:           tests[0].body,
+		HTTPMethod:     "POST",
+		RequestContext: events.APIGatewayProxyRequestContext{Authorizer: rep},
+		Resource:       RootResource,
+	})
+	suite.NoError(err)
+	suite.problem(res, http.StatusServiceUnavailable, CodeQueueFailed)
+}
+
+// jobData decodes the job of a success response
+func (suite *HandlerSuite) jobData(body string) *model.Job {
+	res := &struct{ Data *model.Job }{}
+	suite.NoError(json.Unmarshal([]byte(body), res))
+	suite.NotNil(res.Data)
+	return res.Data
+}
+
+// problem checks the status and code of a problem response
+func (suite *HandlerSuite) problem(res events.APIGatewayProxyResponse, status int, code string) {
+	suite.Equal(status, res.StatusCode)
+	p := &Problem{}
+	suite.NoError(json.Unmarshal([]byte(res.Body), p))
+	suite.Equal(code, p.Code)
+}
+
+// TestHandlerSuite function
+func TestHandlerSuite(t *testing.T) {
+	suite.Run(t, new(HandlerSuite))
+}
+
+// ================================ Helper Methods
+
+// noDB finds no quotes, the job routes only need the quote database for a running job
+type noDB struct{}
+
+func (db noDB) Close() {}
+
+func (db noDB) FetchQuote(string) (*model.Quote, error) { return nil, model.ErrQuoteNotFound }
+
+func (db noDB) FetchQuoteIDByNumber(int) (string, error) { return "", model.ErrQuoteNotFound }
+
+func (db noDB) FetchQuoteIDs(start, end time.Time) ([]string, error) { return nil, nil }
+
+func (db noDB) Ping(ctx context.Context) error { return nil }
+
+func (db noDB) WithLogger(*log.Entry) model.DBHandler { return db }
+
+type memJobs struct {
+	jobs map[string]*model.Job
+}
+
+func (s *memJobs) Close() {}
+
+func (s *memJobs) CreateJob(j *model.Job) error {
+	j.ID = primitive.NewObjectID()
+	s.jobs[j.ID.Hex()] = j
+	return nil
+}
+
+func (s *memJobs) FetchJob(jobID string) (*model.Job, error) {
+	j, ok := s.jobs[jobID]
+	if !ok {
+		return nil, model.ErrJobNotFound
+	}
+	return j, nil
+}
+
+func (s *memJobs) UpdateJob(j *model.Job) error { return nil }
+
+type memQueue struct {
+	err  error
+	sent []string
+}
+
+func (q *memQueue) Send(jobID string) error {
+	if q.err != nil {
+		return q.err
+	}
+	q.sent = append(q.sent, jobID)
+	return nil
+}
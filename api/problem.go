@@ -0,0 +1,182 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/pulpfree/univsales-wrksht-pdf/access"
+	"github.com/pulpfree/univsales-wrksht-pdf/logger"
+	"github.com/pulpfree/univsales-wrksht-pdf/model"
+	"github.com/pulpfree/univsales-wrksht-pdf/service"
+	"github.com/pulpfree/univsales-wrksht-pdf/storage"
+	log "github.com/sirupsen/logrus"
+)
+
+// Error code constants
+const (
+	CodeBadRequest     = "bad_request"
+	CodeBatchFailed    = "batch_failed"
+	CodeEmailFailed    = "email_failed"
+	CodeForbidden      = "forbidden"
+	CodeInternal       = "internal_error"
+	CodeJobNotFound    = "job_not_found"
+	CodeQueueFailed    = "queue_failed"
+	CodeQuoteNotFound  = "quote_not_found"
+	CodeRenderFailed   = "render_failed"
+	CodeUnavailable    = "service_unavailable"
+	CodeUploadFailed   = "upload_failed"
+	CodeValidation     = "validation_failed"
+	CodeWrkshtNotFound = "worksheet_not_found"
+)
+
+// client safe messages for server side failures, the underlying error is only logged
+var serverMessages = map[string]string{
+	CodeEmailFailed:  "Unable to email the worksheet",
+	CodeInternal:     "An unexpected error occurred",
+	CodeQueueFailed:  "Unable to queue the job",
+	CodeRenderFailed: "Unable to render the worksheet",
+	CodeUnavailable:  "A required service is unavailable",
+	CodeUploadFailed: "Unable to store the worksheet",
+}
+
+// Problem struct
+// Error response body, following RFC 7807 application/problem+json
+type Problem struct {
+	Code      string        `json:"code"`
+	Fields    []*FieldError `json:"fields,omitempty"`
+	Message   string        `json:"message"`
+	Status    int           `json:"status"`
+	Timestamp int64         `json:"timestamp"`
+	Title     string        `json:"title"`
+	Type      string        `json:"type"`
+}
+
+// FieldError struct
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error struct
+// An error carrying the http status, code and field details for the problem response
+type Error struct {
+	Code   string
+	Err    error
+	Fields []*FieldError
+	Status int
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap method
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// NewError function
+func NewError(status int, code string, err error) *Error {
+	return &Error{
+		Code:   code,
+		Err:    err,
+		Status: status,
+	}
+}
+
+// ValidationError function
+func ValidationError(fields []*FieldError) *Error {
+	return &Error{
+		Code:   CodeValidation,
+		Err:    errors.New("Request validation failed"),
+		Fields: fields,
+		Status: http.StatusUnprocessableEntity,
+	}
+}
+
+// FromError function
+// Maps model and service errors to the http status and code returned to the client
+func FromError(err error) *Error {
+
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	switch {
+	case errors.Is(err, access.ErrForbidden):
+		return NewError(http.StatusForbidden, CodeForbidden, err)
+	case errors.Is(err, model.ErrInvalidID):
+		return NewError(http.StatusBadRequest, CodeBadRequest, err)
+	case errors.Is(err, model.ErrQuoteNotFound):
+		return NewError(http.StatusNotFound, CodeQuoteNotFound, err)
+	case errors.Is(err, model.ErrJobNotFound):
+		return NewError(http.StatusNotFound, CodeJobNotFound, err)
+	case errors.Is(err, storage.ErrNotFound):
+		return NewError(http.StatusNotFound, CodeWrkshtNotFound, err)
+	}
+
+	var svcErr *service.Error
+	if errors.As(err, &svcErr) {
+		switch svcErr.Op {
+		case service.OpBatch:
+			return NewError(http.StatusUnprocessableEntity, CodeBatchFailed, err)
+		case service.OpEmail:
+			return NewError(http.StatusBadGateway, CodeEmailFailed, err)
+		case service.OpFetch:
+			return NewError(http.StatusServiceUnavailable, CodeUnavailable, err)
+		case service.OpQueue:
+			return NewError(http.StatusServiceUnavailable, CodeQueueFailed, err)
+		case service.OpRender:
+			return NewError(http.StatusInternalServerError, CodeRenderFailed, err)
+		case service.OpUpload:
+			return NewError(http.StatusBadGateway, CodeUploadFailed, err)
+		}
+	}
+
+	return NewError(http.StatusInternalServerError, CodeInternal, err)
+}
+
+// ProblemResponse function
+// Builds the problem+json response for err, server side details are logged rather than returned
+func ProblemResponse(err error, hdrs map[string]string) events.APIGatewayProxyResponse {
+	return problemResponse(err, hdrs, logger.Default())
+}
+
+func problemResponse(err error, hdrs map[string]string, l *log.Entry) events.APIGatewayProxyResponse {
+
+	e := FromError(err)
+
+	p := &Problem{
+		Code:      e.Code,
+		Fields:    e.Fields,
+		Message:   e.Error(),
+		Status:    e.Status,
+		Timestamp: time.Now().Unix(),
+		Title:     http.StatusText(e.Status),
+		Type:      "about:blank",
+	}
+	if e.Status >= http.StatusInternalServerError {
+		l.Errorf("Request failed with code %s: %s", e.Code, err)
+		if msg, ok := serverMessages[e.Code]; ok {
+			p.Message = msg
+		} else {
+			p.Message = serverMessages[CodeInternal]
+		}
+	} else {
+		l.Warnf("Request rejected with code %s: %s", e.Code, err)
+	}
+
+	resHdrs := make(map[string]string, len(hdrs)+1)
+	for k, v := range hdrs {
+		resHdrs[k] = v
+	}
+	resHdrs["Content-Type"] = "application/problem+json"
+
+	body, _ := json.Marshal(p)
+
+	return events.APIGatewayProxyResponse{Body: string(body), Headers: resHdrs, StatusCode: e.Status}
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pulpfree/univsales-wrksht-pdf/email"
+	"github.com/pulpfree/univsales-wrksht-pdf/pdf"
+	"github.com/pulpfree/univsales-wrksht-pdf/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxBatchQuotes is the largest number of quote ids accepted in a batch request
+const MaxBatchQuotes = service.MaxBatchQuotes
+
+// DecodeRequest function
+// Strictly decodes and validates a worksheet request body
+func DecodeRequest(body string) (*pdf.Request, error) {
+
+	r := &pdf.Request{}
+	if err := decode(body, r); err != nil {
+		return nil, err
+	}
+
+	fields := []*FieldError{}
+	fields = append(fields, validateObjectID("quoteID", r.QuoteID)...)
+
+	switch r.Delivery {
+	case "", pdf.DeliveryS3:
+	case pdf.DeliveryInline:
+		if r.Async {
+			fields = append(fields, &FieldError{Field: "delivery", Message: "inline delivery cannot be combined with async"})
+		}
+	default:
+		fields = append(fields, &FieldError{Field: "delivery", Message: fmt.Sprintf("must be one of: %s, %s", pdf.DeliveryS3, pdf.DeliveryInline)})
+	}
+
+	if r.Email != nil {
+		fields = append(fields, validateEmail(r)...)
+	}
+	fields = append(fields, validateOptions(r.Options)...)
+
+	if len(fields) > 0 {
+		return nil, ValidationError(fields)
+	}
+
+	return r, nil
+}
+
+// DecodeBatchRequest function
+// Strictly decodes and validates a batch request body
+func DecodeBatchRequest(body string) (*service.BatchRequest, error) {
+
+	r := &service.BatchRequest{}
+	if err := decode(body, r); err != nil {
+		return nil, err
+	}
+
+	fields := []*FieldError{}
+
+	switch {
+	case len(r.QuoteIDs) > 0 && r.DateRange != nil:
+		fields = append(fields, &FieldError{Field: "quoteIDs", Message: "cannot be combined with dateRange"})
+	case len(r.QuoteIDs) == 0 && r.DateRange == nil:
+		fields = append(fields, &FieldError{Field: "quoteIDs", Message: "either quoteIDs or dateRange is required"})
+	case len(r.QuoteIDs) > MaxBatchQuotes:
+		fields = append(fields, &FieldError{Field: "quoteIDs", Message: fmt.Sprintf("must not contain more than %d ids", MaxBatchQuotes)})
+	}
+
+	for i, id := range r.QuoteIDs {
+		fields = append(fields, validateObjectID(fmt.Sprintf("quoteIDs[%d]", i), id)...)
+	}
+
+	if r.DateRange != nil {
+		if r.DateRange.Start.IsZero() {
+			fields = append(fields, &FieldError{Field: "dateRange.start", Message: "is required"})
+		}
+		if r.DateRange.End.IsZero() {
+			fields = append(fields, &FieldError{Field: "dateRange.end", Message: "is required"})
+		} else if !r.DateRange.End.After(r.DateRange.Start) {
+			fields = append(fields, &FieldError{Field: "dateRange.end", Message: "must be after start"})
+		}
+	}
+
+	fields = append(fields, validateOptions(r.Options)...)
+
+	switch r.Format {
+	case "", service.BatchZip, service.BatchMerged:
+	default:
+		fields = append(fields, &FieldError{Field: "format", Message: fmt.Sprintf("must be one of: %s, %s", service.BatchZip, service.BatchMerged)})
+	}
+
+	if len(fields) > 0 {
+		return nil, ValidationError(fields)
+	}
+
+	return r, nil
+}
+
+// ================================ Helper Functions
+
+// decode rejects empty bodies, unknown fields and trailing data
+func decode(body string, v interface{}) error {
+
+	if strings.TrimSpace(body) == "" {
+		return NewError(http.StatusBadRequest, CodeBadRequest, errors.New("Request body is required"))
+	}
+
+	dec := json.NewDecoder(strings.NewReader(body))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return NewError(http.StatusBadRequest, CodeBadRequest, fmt.Errorf("Invalid request body: %s", err))
+	}
+	if dec.More() {
+		return NewError(http.StatusBadRequest, CodeBadRequest, errors.New("Request body must contain a single JSON object"))
+	}
+
+	return nil
+}
+
+func validateEmail(r *pdf.Request) []*FieldError {
+
+	fields := []*FieldError{}
+	if r.Delivery == pdf.DeliveryInline {
+		fields = append(fields, &FieldError{Field: "email", Message: "email cannot be combined with inline delivery"})
+	}
+	for i, rcpt := range r.Email.Recipients {
+		if err := email.ValidateRecipient(rcpt); err != nil {
+			fields = append(fields, &FieldError{Field: fmt.Sprintf("email.recipients[%d]", i), Message: err.Error()})
+		}
+	}
+	if r.Email.Template != "" && !email.ValidTemplate(r.Email.Template) {
+		fields = append(fields, &FieldError{Field: "email.template", Message: fmt.Sprintf("must be one of: %s, %s", email.TemplateCustomer, email.TemplateInstaller)})
+	}
+
+	return fields
+}
+
+func validateOptions(o *pdf.Options) []*FieldError {
+
+	fields := []*FieldError{}
+	if o == nil {
+		return fields
+	}
+
+	oneOf := func(field, v string, valid []string) {
+		if v != "" && !contains(valid, v) {
+			fields = append(fields, &FieldError{Field: field, Message: "must be one of: " + strings.Join(valid, ", ")})
+		}
+	}
+	oneOf("options.paperSize", o.PaperSize, pdf.PaperSizes)
+	oneOf("options.orientation", o.Orientation, pdf.Orientations)
+	oneOf("options.sort", o.Sort, pdf.SortOrders)
+	for i, sec := range o.Sections {
+		oneOf(fmt.Sprintf("options.sections[%d]", i), sec, pdf.Sections)
+	}
+
+	if o.FontScale != 0 && (o.FontScale < pdf.MinFontScale || o.FontScale > pdf.MaxFontScale) {
+		fields = append(fields, &FieldError{Field: "options.fontScale", Message: fmt.Sprintf("must be between %g and %g", pdf.MinFontScale, pdf.MaxFontScale)})
+	}
+
+	return fields
+}
+
+func contains(l []string, v string) bool {
+	for _, s := range l {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func validateObjectID(field, id string) []*FieldError {
+
+	if id == "" {
+		return []*FieldError{{Field: field, Message: "is required"}}
+	}
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return []*FieldError{{Field: field, Message: "must be a 24 character hex ObjectID"}}
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/pulpfree/univsales-wrksht-pdf/model"
+	"github.com/pulpfree/univsales-wrksht-pdf/service"
+	"github.com/stretchr/testify/suite"
+)
+
+// UnitSuite struct
+type UnitSuite struct {
+	suite.Suite
+}
+
+const quoteID = "5ccc90913c4a256251cf326b"
+
+func (suite *UnitSuite) TestDecodeRequest() {
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		field  string
+	}{
+		{"valid", `{"quoteID":"` + quoteID + `"}`, 0, ""},
+		{"valid inline", `{"quoteID":"` + quoteID + `","delivery":"inline"}`, 0, ""},
+		{"empty body", ``, http.StatusBadRequest, ""},
+		{"malformed", `{"quoteID":`, http.StatusBadRequest, ""},
+		{"unknown field", `{"quoteID":"` + quoteID + `","bogus":1}`, http.StatusBadRequest, ""},
+		{"trailing data", `{"quoteID":"` + quoteID + `"} {}`, http.StatusBadRequest, ""},
+		{"null body", `null`, http.StatusUnprocessableEntity, "quoteID"},
+		{"missing quoteID", `{}`, http.StatusUnprocessableEntity, "quoteID"},
+		{"bad quoteID", `{"quoteID":"abc"}`, http.StatusUnprocessableEntity, "quoteID"},
+		{"bad delivery", `{"quoteID":"` + quoteID + `","delivery":"fax"}`, http.StatusUnprocessableEntity, "delivery"},
+		{"inline async", `{"quoteID":"` + quoteID + `","delivery":"inline","async":true}`, http.StatusUnprocessableEntity, "delivery"},
+		{"valid email", `{"quoteID":"` + quoteID + `","email":{"recipients":["customer","crew@example.com"],"template":"customer","attach":true}}`, 0, ""},
+		{"inline email", `{"quoteID":"` + quoteID + `","delivery":"inline","email":{}}`, http.StatusUnprocessableEntity, "email"},
+		{"bad recipient", `{"quoteID":"` + quoteID + `","email":{"recipients":["installers","crew"]}}`, http.StatusUnprocessableEntity, "email.recipients[1]"},
+		{"valid options", `{"quoteID":"` + quoteID + `","options":{"paperSize":"A4","orientation":"landscape","sections":["windows","notes"],"sort":"room","fontScale":1.2}}`, 0, ""},
+		{"bad paper size", `{"quoteID":"` + quoteID + `","options":{"paperSize":"A3"}}`, http.StatusUnprocessableEntity, "options.paperSize"},
+		{"bad section", `{"quoteID":"` + quoteID + `","options":{"sections":["groups","prices"]}}`, http.StatusUnprocessableEntity, "options.sections[1]"},
+		{"bad font scale", `{"quoteID":"` + quoteID + `","options":{"fontScale":3}}`, http.StatusUnprocessableEntity, "options.fontScale"},
+		{"bad template", `{"quoteID":"` + quoteID + `","email":{"template":"memo"}}`, http.StatusUnprocessableEntity, "email.template"},
+	}
+
+	for _, tt := range tests {
+		r, err := DecodeRequest(tt.body)
+		if tt.status == 0 {
+			suite.NoError(err, tt.name)
+			suite.Equal(quoteID, r.QuoteID, tt.name)
+			continue
+		}
+		e := FromError(err)
+		suite.Equal(tt.status, e.Status, tt.name)
+		if tt.field != "" {
+			suite.Len(e.Fields, 1, tt.name)
+			suite.Equal(tt.field, e.Fields[0].Field, tt.name)
+		}
+	}
+}
+
+func (suite *UnitSuite) TestDecodeBatchRequest() {
+
+	ids := make([]string, MaxBatchQuotes+1)
+	for i := range ids {
+		ids[i] = quoteID
+	}
+	tooMany, _ := json.Marshal(map[string][]string{"quoteIDs": ids})
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		field  string
+	}{
+		{"quote ids", `{"quoteIDs":["` + quoteID + `"]}`, 0, ""},
+		{"date range", `{"dateRange":{"start":"2020-10-05T00:00:00Z","end":"2020-10-12T00:00:00Z"},"format":"merged"}`, 0, ""},
+		{"neither", `{}`, http.StatusUnprocessableEntity, "quoteIDs"},
+		{"both", `{"quoteIDs":["` + quoteID + `"],"dateRange":{"start":"2020-10-05T00:00:00Z","end":"2020-10-12T00:00:00Z"}}`, http.StatusUnprocessableEntity, "quoteIDs"},
+		{"bad id", `{"quoteIDs":["` + quoteID + `","xyz"]}`, http.StatusUnprocessableEntity, "quoteIDs[1]"},
+		{"reversed range", `{"dateRange":{"start":"2020-10-12T00:00:00Z","end":"2020-10-05T00:00:00Z"}}`, http.StatusUnprocessableEntity, "dateRange.end"},
+		{"bad format", `{"quoteIDs":["` + quoteID + `"],"format":"tar"}`, http.StatusUnprocessableEntity, "format"},
+		{"too many", string(tooMany), http.StatusUnprocessableEntity, "quoteIDs"},
+	}
+
+	for _, tt := range tests {
+		_, err := DecodeBatchRequest(tt.body)
+		if tt.status == 0 {
+			suite.NoError(err, tt.name)
+			continue
+		}
+		e := FromError(err)
+		suite.Equal(tt.status, e.Status, tt.name)
+		suite.Len(e.Fields, 1, tt.name)
+		suite.Equal(tt.field, e.Fields[0].Field, tt.name)
+	}
+}
+
+func (suite *UnitSuite) TestFromError() {
+
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{fmt.Errorf("%w: abc", model.ErrInvalidID), http.StatusBadRequest, CodeBadRequest},
+		{&service.Error{Op: service.OpFetch, Err: fmt.Errorf("%w: abc", model.ErrQuoteNotFound)}, http.StatusNotFound, CodeQuoteNotFound},
+		{model.ErrJobNotFound, http.StatusNotFound, CodeJobNotFound},
+		{&service.Error{Op: service.OpFetch, Err: errors.New("timeout")}, http.StatusServiceUnavailable, CodeUnavailable},
+		{&service.Error{Op: service.OpRender, Err: errors.New("bad spec")}, http.StatusInternalServerError, CodeRenderFailed},
+		{&service.Error{Op: service.OpUpload, Err: errors.New("denied")}, http.StatusBadGateway, CodeUploadFailed},
+		{&service.Error{Op: service.OpEmail, Err: errors.New("rejected")}, http.StatusBadGateway, CodeEmailFailed},
+		{errors.New("boom"), http.StatusInternalServerError, CodeInternal},
+	}
+
+	for _, tt := range tests {
+		e := FromError(tt.err)
+		suite.Equal(tt.status, e.Status, tt.err.Error())
+		suite.Equal(tt.code, e.Code, tt.err.Error())
+	}
+}
+
+func (suite *UnitSuite) TestProblemResponse() {
+
+	hdrs := map[string]string{"Content-Type": "application/json"}
+	res := ProblemResponse(&service.Error{Op: service.OpUpload, Err: errors.New("secret bucket detail")}, hdrs)
+
+	suite.Equal(http.StatusBadGateway, res.StatusCode)
+	suite.Equal("application/problem+json", res.Headers["Content-Type"])
+	suite.Equal("application/json", hdrs["Content-Type"])
+
+	p := &Problem{}
+	suite.NoError(json.Unmarshal([]byte(res.Body), p))
+	suite.Equal(CodeUploadFailed, p.Code)
+	suite.Equal(http.StatusBadGateway, p.Status)
+	suite.NotContains(p.Message, "secret")
+}
+
+// TestUnitSuite function
+func TestUnitSuite(t *testing.T) {
+	suite.Run(t, new(UnitSuite))
+}
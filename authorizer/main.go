@@ -23,76 +23,83 @@ import (
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
-	auth "github.com/pulpfree/lambda-go-auth"
+	"github.com/pulpfree/univsales-wrksht-pdf/cognito"
+	"github.com/pulpfree/univsales-wrksht-pdf/config"
+	"github.com/pulpfree/univsales-wrksht-pdf/policy"
 	log "github.com/sirupsen/logrus"
 )
 
-// Ensure you set these based on the cognito user pool
-const (
-	cognitoPoolID = "ca-central-1_1DQjnU6jd"
-	cognitoRegion = "ca-central-1"
+// Set in main from config, once per cold start
+var (
+	policies        policy.Table
+	servicePolicies policy.Table
+	validator       cognito.Validator
 )
 
-func getJWKURL() string {
-	return fmt.Sprintf("https://cognito-idp.%s.amazonaws.com/%s/.well-known/jwks.json", cognitoRegion, cognitoPoolID)
-}
-
-func handleRequest(ctx context.Context, event events.APIGatewayCustomAuthorizerRequest) (events.APIGatewayCustomAuthorizerResponse, error) {
+func handleRequest(ctx context.Context, event events.APIGatewayCustomAuthorizerRequest) (Response, error) {
 
 	// validate the incoming token
 	// and produce the principal user identifier associated with the token
-	principalID, err := auth.Validate(event.AuthorizationToken, getJWKURL())
+	token, err := validator.Validate(strings.TrimPrefix(event.AuthorizationToken, "Bearer "))
 	if err != nil {
-		log.Errorf("Error in token validation: %+v", err.Error())
-		return events.APIGatewayCustomAuthorizerResponse{}, errors.New("Unauthorized")
+		log.Errorf("Error in token validation: %s", err)
+		return Response{}, errors.New("Unauthorized")
 	}
 
-	// this could be accomplished in a number of ways:
-	// 1. Call out to OAuth provider
-	// 2. Decode a JWT token inline
-	// 3. Lookup in a self-managed DB
-	// principalID := "user|a1b2c3d4"
-	// you can send a 401 Unauthorized response to the client by failing like so:
-	// return events.APIGatewayCustomAuthorizerResponse{}, errors.New("Unauthorized")
-
-	// if the token is valid, a policy must be generated which will allow or deny access to the client
-
-	// if access is denied, the client will receive a 403 Access Denied response
-	// if access is allowed, API Gateway will proceed with the backend integration configured on the method that was called
-
-	// this function must generate a policy that is associated with the recognized principal user identifier.
-	// depending on your use case, you might store policies in a DB, or generate them on the fly
-
 	// keep in mind, the policy is cached for 5 minutes by default (TTL is configurable in the authorizer)
 	// and will apply to subsequent calls to any method/resource in the RestApi
-	// made with the same token
-
-	//the example policy below denies access to all resources in the RestApi
+	// made with the same token, so it lists every resource the groups may use rather than the one called
 	tmp := strings.Split(event.MethodArn, ":")
 	apiGatewayArnTmp := strings.Split(tmp[5], "/")
 	awsAccountID := tmp[4]
 
-	resp := NewAuthorizerResponse(principalID, awsAccountID)
+	resp := NewAuthorizerResponse(token.Principal, awsAccountID)
 	resp.Region = tmp[3]
 	resp.APIID = apiGatewayArnTmp[0]
 	resp.Stage = apiGatewayArnTmp[1]
-	// resp.DenyAllMethods()
-	// resp.AllowMethod(Get, "/pets/*")
-	resp.AllowAllMethods()
 
-	// new! -- add additional key-value pairs associated with the authenticated principal
-	// these are made available by APIGW like so: $context.authorizer.<key>
-	// additional context is cached
-	/* resp.Context = map[string]interface{}{
-		"stringKey":  "stringval",
-		"numberKey":  123,
-		"booleanKey": true,
-	} */
+	// services have a policy of their own, people get the policies of their groups
+	id := token.Identity
+	if id.Caller == cognito.CallerService {
+		applyPolicy(servicePolicies, resp, []string{id.Name})
+	} else {
+		applyPolicy(policies, resp, id.Groups)
+	}
+	log.WithFields(log.Fields{"caller": id.Caller, "groups": id.Groups, "user_id": id.UserID}).Infof("Authorized %s", token.Principal)
+
+	// made available to the handler as req.RequestContext.Authorizer, and cached with the policy
+	resp.Context = id.Context()
 
-	return resp.APIGatewayCustomAuthorizerResponse, nil
+	return resp.Build(), nil
 }
 
+// main loads and validates the pool settings and policy table once per cold start,
+// in main rather than init so the tests run without config
 func main() {
+	cfg := &config.Config{}
+	err := cfg.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	pool, err := cognito.NewSettings(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// the keys are cached for the life of the container rather than fetched per request
+	validator = cognito.NewValidator(pool, cognito.NewJWKS(pool.JWKSURL(), cognito.DefaultJWKSTTL))
+	policies, err = policy.NewTable(cfg.AuthPolicies)
+	if err != nil {
+		log.Fatal(err)
+	}
+	servicePolicies, err = policy.NewTable(cfg.ServicePolicies)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, name := range pool.Services {
+		if len(servicePolicies[name]) == 0 {
+			log.Warnf("Service %s has no policy and will be denied", name)
+		}
+	}
 	lambda.Start(handleRequest)
 }
 
@@ -152,9 +159,39 @@ func (e Effect) String() string {
 	return ""
 }
 
+// Condition type
+// IAM condition operators mapped to their condition keys and values,
+// as in {"IpAddress": {"aws:SourceIp": ["10.0.0.0/8"]}}
+type Condition map[string]map[string]interface{}
+
+// Statement struct
+// An IAM policy statement. The events package has no condition element, so the response has types of its own
+type Statement struct {
+	Action    []string
+	Condition Condition `json:",omitempty"`
+	Effect    string
+	Resource  []string
+}
+
+// PolicyDocument struct
+type PolicyDocument struct {
+	Version   string
+	Statement []Statement
+}
+
+// Response struct
+// The authorizer response returned to API Gateway, matching events.APIGatewayCustomAuthorizerResponse
+type Response struct {
+	PrincipalID        string                 `json:"principalId"`
+	PolicyDocument     PolicyDocument         `json:"policyDocument"`
+	Context            map[string]interface{} `json:"context,omitempty"`
+	UsageIdentifierKey string                 `json:"usageIdentifierKey,omitempty"`
+}
+
 // AuthorizerResponse struct
+// Collects the allowed and denied methods, Build returns the response with the policy document
 type AuthorizerResponse struct {
-	events.APIGatewayCustomAuthorizerResponse
+	Response
 
 	// The region where the API is deployed. By default this is set to '*'
 	Region string
@@ -167,16 +204,25 @@ type AuthorizerResponse struct {
 
 	// The name of the stage used in the policy. By default this is set to '*'
 	Stage string
+
+	allow []method
+	deny  []method
+}
+
+// method is an allowed or denied method ARN, with the conditions of the statement
+type method struct {
+	arn        string
+	conditions Condition
 }
 
+// policyVersion is the IAM policy language version
+const policyVersion = "2012-10-17"
+
 // NewAuthorizerResponse function
 func NewAuthorizerResponse(principalID string, AccountID string) *AuthorizerResponse {
 	return &AuthorizerResponse{
-		APIGatewayCustomAuthorizerResponse: events.APIGatewayCustomAuthorizerResponse{
+		Response: Response{
 			PrincipalID: principalID,
-			PolicyDocument: events.APIGatewayCustomAuthorizerPolicy{
-				Version: "2012-10-17",
-			},
 		},
 		Region:    "*",
 		AccountID: AccountID,
@@ -185,40 +231,99 @@ func NewAuthorizerResponse(principalID string, AccountID string) *AuthorizerResp
 	}
 }
 
-func (r *AuthorizerResponse) addMethod(effect Effect, verb HTTPVerb, resource string) {
-	resourceArn := "arn:aws:execute-api:" +
-		r.Region + ":" +
-		r.AccountID + ":" +
-		r.APIID + "/" +
-		r.Stage + "/" +
-		verb.String() + "/" +
-		strings.TrimLeft(resource, "/")
-
-	s := events.IAMPolicyStatement{
-		Effect:   effect.String(),
-		Action:   []string{"execute-api:Invoke"},
-		Resource: []string{resourceArn},
-	}
-
-	r.PolicyDocument.Statement = append(r.PolicyDocument.Statement, s)
-}
-
 // AllowAllMethods method
 func (r *AuthorizerResponse) AllowAllMethods() {
-	r.addMethod(Allow, All, "*")
+	r.addMethod(Allow, All, "*", nil)
 }
 
 // DenyAllMethods method
 func (r *AuthorizerResponse) DenyAllMethods() {
-	r.addMethod(Deny, All, "*")
+	r.addMethod(Deny, All, "*", nil)
 }
 
 // AllowMethod method
 func (r *AuthorizerResponse) AllowMethod(verb HTTPVerb, resource string) {
-	r.addMethod(Allow, verb, resource)
+	r.addMethod(Allow, verb, resource, nil)
 }
 
 // DenyMethod method
 func (r *AuthorizerResponse) DenyMethod(verb HTTPVerb, resource string) {
-	r.addMethod(Deny, verb, resource)
+	r.addMethod(Deny, verb, resource, nil)
+}
+
+// AllowMethodWithConditions method
+func (r *AuthorizerResponse) AllowMethodWithConditions(verb HTTPVerb, resource string, conditions Condition) {
+	r.addMethod(Allow, verb, resource, conditions)
+}
+
+// DenyMethodWithConditions method
+func (r *AuthorizerResponse) DenyMethodWithConditions(verb HTTPVerb, resource string, conditions Condition) {
+	r.addMethod(Deny, verb, resource, conditions)
+}
+
+// Build method
+// Returns the response with the policy document. The methods of each effect without conditions
+// are merged into a single statement listing their resources, and each method with conditions
+// has a statement of its own. Allow statements come before deny statements
+func (r *AuthorizerResponse) Build() Response {
+
+	res := r.Response
+	res.PolicyDocument = PolicyDocument{Version: policyVersion}
+	res.PolicyDocument.Statement = append(statements(Allow, r.allow), statements(Deny, r.deny)...)
+
+	return res
+}
+
+// ================================ Helper Methods
+
+func (r *AuthorizerResponse) addMethod(effect Effect, verb HTTPVerb, resource string, conditions Condition) {
+
+	m := method{arn: r.methodArn(verb, resource), conditions: conditions}
+	if effect == Allow {
+		r.allow = append(r.allow, m)
+	} else {
+		r.deny = append(r.deny, m)
+	}
+}
+
+// methodArn returns the execute-api ARN of the method,
+// arn:aws:execute-api:region:account:api/stage/VERB/resource
+func (r *AuthorizerResponse) methodArn(verb HTTPVerb, resource string) string {
+	return fmt.Sprintf("arn:aws:execute-api:%s:%s:%s/%s/%s/%s",
+		r.Region, r.AccountID, r.APIID, r.Stage, verb, strings.TrimLeft(resource, "/"))
+}
+
+// ================================ Helper Functions
+
+func statements(effect Effect, methods []method) []Statement {
+
+	var merged *Statement
+	var list []Statement
+	seen := map[string]bool{}
+	for _, m := range methods {
+		s := Statement{
+			Action:    []string{"execute-api:Invoke"},
+			Condition: m.conditions,
+			Effect:    effect.String(),
+			Resource:  []string{m.arn},
+		}
+		if len(m.conditions) > 0 {
+			list = append(list, s)
+			continue
+		}
+		if seen[m.arn] {
+			continue
+		}
+		seen[m.arn] = true
+		if merged == nil {
+			merged = &s
+			continue
+		}
+		merged.Resource = append(merged.Resource, m.arn)
+	}
+	if merged != nil {
+		list = append([]Statement{*merged}, list...)
+	}
+
+	return list
 }
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	jwt "github.com/golang-jwt/jwt/v4"
+	"github.com/pulpfree/univsales-wrksht-pdf/access"
+	"github.com/pulpfree/univsales-wrksht-pdf/cognito"
+	"github.com/pulpfree/univsales-wrksht-pdf/cognito/cognitotest"
+	"github.com/pulpfree/univsales-wrksht-pdf/model"
+	"github.com/pulpfree/univsales-wrksht-pdf/policy"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	serviceClientID = "7s8e9r0v1i2c3e4c5l6i7e"
+	methodArn       = "arn:aws:execute-api:ca-central-1:123456789012:abc123/Prod/POST/"
+)
+
+// HandlerSuite struct
+type HandlerSuite struct {
+	suite.Suite
+	key   *rsa.PrivateKey
+	other *rsa.PrivateKey
+	pool  *cognito.Settings
+}
+
+// SetupSuite method
+func (suite *HandlerSuite) SetupSuite() {
+	var err error
+	suite.key, err = cognitotest.NewKey()
+	suite.NoError(err)
+	suite.other, err = cognitotest.NewKey()
+	suite.NoError(err)
+
+	suite.pool = &cognito.Settings{
+		ClientIDs: []string{cognitotest.ClientID},
+		PoolID:    cognitotest.PoolID,
+		Region:    cognitotest.Region,
+		Services:  map[string]string{serviceClientID: "scheduler"},
+		TokenUse:  []string{cognito.TokenUseID},
+	}
+	validator = cognito.NewValidator(suite.pool, cognito.LocalKeys{cognitotest.KeyID: &suite.key.PublicKey})
+	policies, err = policy.NewTable(map[string][]string{"installers": {"GET /worksheets/*"}, "sales": {"POST /", "GET /jobs/*"}})
+	suite.NoError(err)
+	servicePolicies, err = policy.NewTable(map[string][]string{"scheduler": {"POST /"}})
+	suite.NoError(err)
+}
+
+// TestAuthorized method
+func (suite *HandlerSuite) TestAuthorized() {
+
+	res, err := suite.authorize(suite.sign(cognitotest.IDClaims(nil), suite.key))
+	suite.NoError(err)
+	suite.Equal("jdoe|"+cognitotest.ClientID, res.PrincipalID)
+	suite.Len(res.PolicyDocument.Statement, 1)
+	suite.Equal("Allow", res.PolicyDocument.Statement[0].Effect)
+	suite.Equal([]string{
+		"arn:aws:execute-api:ca-central-1:123456789012:abc123/Prod/POST/",
+		"arn:aws:execute-api:ca-central-1:123456789012:abc123/Prod/GET/jobs/*",
+	}, res.PolicyDocument.Statement[0].Resource)
+	suite.Equal("8f1c-42", res.Context[cognito.ContextUserID])
+	suite.Equal("sales", res.Context[cognito.ContextGroups])
+	suite.Equal(cognito.CallerUser, res.Context[cognito.ContextCaller])
+	suite.Equal("hamilton", res.Context[cognito.ContextBranches])
+	suite.Equal(access.RoleManager, res.Context[cognito.ContextRole])
+
+	// a user outside the policy table is denied everything
+	res, err = suite.authorize(suite.sign(cognitotest.IDClaims(jwt.MapClaims{"cognito:groups": []string{"contractors"}}), suite.key))
+	suite.NoError(err)
+	suite.Len(res.PolicyDocument.Statement, 1)
+	suite.Equal("Deny", res.PolicyDocument.Statement[0].Effect)
+}
+
+// TestService method
+func (suite *HandlerSuite) TestService() {
+
+	// client credentials tokens have no user or groups
+	claims := cognitotest.ServiceClaims(serviceClientID, nil)
+	res, err := suite.authorize(suite.sign(claims, suite.key))
+	suite.NoError(err)
+	suite.Equal("scheduler|"+serviceClientID, res.PrincipalID)
+	suite.Len(res.PolicyDocument.Statement, 1)
+	suite.Equal("Allow", res.PolicyDocument.Statement[0].Effect)
+	suite.Equal([]string{"arn:aws:execute-api:ca-central-1:123456789012:abc123/Prod/POST/"}, res.PolicyDocument.Statement[0].Resource)
+	suite.Equal(cognito.CallerService, res.Context[cognito.ContextCaller])
+	suite.Equal("scheduler", res.Context[cognito.ContextName])
+
+	// a service cannot take on the groups of a user
+	claims["cognito:groups"] = []string{"admins"}
+	res, err = suite.authorize(suite.sign(claims, suite.key))
+	suite.NoError(err)
+	suite.Len(res.PolicyDocument.Statement, 1)
+	suite.Equal([]string{"arn:aws:execute-api:ca-central-1:123456789012:abc123/Prod/POST/"}, res.PolicyDocument.Statement[0].Resource)
+
+	claims["username"] = "jdoe"
+	_, err = suite.authorize(suite.sign(claims, suite.key))
+	suite.EqualError(err, "Unauthorized")
+}
+
+// TestUnauthorized method
+func (suite *HandlerSuite) TestUnauthorized() {
+
+	tests := map[string]string{
+		"expired":        suite.sign(cognitotest.IDClaims(jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}), suite.key),
+		"wrong issuer":   suite.sign(cognitotest.IDClaims(jwt.MapClaims{"iss": "https://cognito-idp.ca-central-1.amazonaws.com/ca-central-1_other"}), suite.key),
+		"wrong audience": suite.sign(cognitotest.IDClaims(jwt.MapClaims{"aud": "otherclient"}), suite.key),
+		"bad signature":  suite.sign(cognitotest.IDClaims(nil), suite.other),
+		"empty":          "",
+	}
+
+	for name, token := range tests {
+		_, err := suite.authorize(token)
+		suite.EqualError(err, "Unauthorized", name)
+	}
+}
+
+// TestQuoteAccess method
+// The branch and role reach the quote checks only from id tokens, user access tokens carry neither
+func (suite *HandlerSuite) TestQuoteAccess() {
+
+	_, err := suite.authorize(suite.sign(cognitotest.Claims(nil), suite.key))
+	suite.EqualError(err, "Unauthorized")
+
+	res, err := suite.authorize(suite.sign(cognitotest.IDClaims(nil), suite.key))
+	suite.NoError(err)
+	id := cognito.IdentityFromContext(res.Context)
+	suite.NoError(access.Check(id, &model.Quote{Branch: "hamilton", Number: 1042}))
+	suite.True(errors.Is(access.Check(id, &model.Quote{Branch: "burlington", Number: 1043}), access.ErrForbidden))
+
+	res, err = suite.authorize(suite.sign(cognitotest.IDClaims(jwt.MapClaims{"custom:role": access.RoleRep}), suite.key))
+	suite.NoError(err)
+	id = cognito.IdentityFromContext(res.Context)
+	suite.NoError(access.Check(id, &model.Quote{Branch: "hamilton", Number: 1042, SalesRepID: cognitotest.UserID}))
+	suite.True(errors.Is(access.Check(id, &model.Quote{Branch: "hamilton", Number: 1044, SalesRepID: "rep-2"}), access.ErrForbidden))
+}
+
+// ================================ Helper Methods
+
+func (suite *HandlerSuite) authorize(token string) (Response, error) {
+	return handleRequest(context.Background(), events.APIGatewayCustomAuthorizerRequest{
+		AuthorizationToken: token,
+		MethodArn:          methodArn,
+		Type:               "TOKEN",
+	})
+}
+
+func (suite *HandlerSuite) sign(claims jwt.MapClaims, key *rsa.PrivateKey) string {
+	s, err := cognitotest.Sign(claims, cognitotest.KeyID, key)
+	suite.NoError(err)
+	return s
+}
+
+// ResponseSuite struct
+type ResponseSuite struct {
+	suite.Suite
+}
+
+// TestBuild method
+func (suite *ResponseSuite) TestBuild() {
+
+	ipCondition := Condition{"IpAddress": {"aws:SourceIp": []string{"10.0.0.0/8"}}}
+
+	tests := []struct {
+		name  string
+		build func(r *AuthorizerResponse)
+		want  string
+	}{
+		{
+			name:  "allow all",
+			build: func(r *AuthorizerResponse) { r.AllowAllMethods() },
+			want: `[{"Action":["execute-api:Invoke"],"Effect":"Allow","Resource":[
+				"arn:aws:execute-api:ca-central-1:123456789012:abc123/Prod/*/*"]}]`,
+		},
+		{
+			name:  "deny all",
+			build: func(r *AuthorizerResponse) { r.DenyAllMethods() },
+			want: `[{"Action":["execute-api:Invoke"],"Effect":"Deny","Resource":[
+				"arn:aws:execute-api:ca-central-1:123456789012:abc123/Prod/*/*"]}]`,
+		},
+		{
+			name: "allows merged in order, duplicates dropped",
+			build: func(r *AuthorizerResponse) {
+				r.AllowMethod(Post, "/")
+				r.AllowMethod(Get, "/jobs/*")
+				r.AllowMethod(Post, "/batch")
+				r.AllowMethod(Get, "jobs/*")
+			},
+			want: `[{"Action":["execute-api:Invoke"],"Effect":"Allow","Resource":[
+				"arn:aws:execute-api:ca-central-1:123456789012:abc123/Prod/POST/",
+				"arn:aws:execute-api:ca-central-1:123456789012:abc123/Prod/GET/jobs/*",
+				"arn:aws:execute-api:ca-central-1:123456789012:abc123/Prod/POST/batch"]}]`,
+		},
+		{
+			name: "allow before deny",
+			build: func(r *AuthorizerResponse) {
+				r.DenyMethod(Delete, "/jobs/*")
+				r.AllowMethod(All, "/jobs/*")
+				r.DenyMethod(Put, "/jobs/*")
+			},
+			want: `[
+				{"Action":["execute-api:Invoke"],"Effect":"Allow","Resource":[
+					"arn:aws:execute-api:ca-central-1:123456789012:abc123/Prod/*/jobs/*"]},
+				{"Action":["execute-api:Invoke"],"Effect":"Deny","Resource":[
+					"arn:aws:execute-api:ca-central-1:123456789012:abc123/Prod/DELETE/jobs/*",
+					"arn:aws:execute-api:ca-central-1:123456789012:abc123/Prod/PUT/jobs/*"]}]`,
+		},
+		{
+			name: "conditions in statements of their own",
+			build: func(r *AuthorizerResponse) {
+				r.AllowMethod(Get, "/health")
+				r.AllowMethodWithConditions(Post, "/batch", ipCondition)
+				r.AllowMethod(Get, "/jobs/*")
+				r.DenyMethodWithConditions(Post, "/", Condition{"Bool": {"aws:SecureTransport": "false"}})
+			},
+			want: `[
+				{"Action":["execute-api:Invoke"],"Effect":"Allow","Resource":[
+					"arn:aws:execute-api:ca-central-1:123456789012:abc123/Prod/GET/health",
+					"arn:aws:execute-api:ca-central-1:123456789012:abc123/Prod/GET/jobs/*"]},
+				{"Action":["execute-api:Invoke"],"Condition":{"IpAddress":{"aws:SourceIp":["10.0.0.0/8"]}},"Effect":"Allow","Resource":[
+					"arn:aws:execute-api:ca-central-1:123456789012:abc123/Prod/POST/batch"]},
+				{"Action":["execute-api:Invoke"],"Condition":{"Bool":{"aws:SecureTransport":"false"}},"Effect":"Deny","Resource":[
+					"arn:aws:execute-api:ca-central-1:123456789012:abc123/Prod/POST/"]}]`,
+		},
+		{
+			name:  "no methods",
+			build: func(r *AuthorizerResponse) {},
+			want:  `null`,
+		},
+	}
+
+	for _, tt := range tests {
+		r := NewAuthorizerResponse("jdoe|client", "123456789012")
+		r.Region = "ca-central-1"
+		r.APIID = "abc123"
+		r.Stage = "Prod"
+		tt.build(r)
+
+		res := r.Build()
+		suite.Equal("2012-10-17", res.PolicyDocument.Version, tt.name)
+		body, err := json.Marshal(res.PolicyDocument.Statement)
+		suite.NoError(err)
+		suite.JSONEq(tt.want, string(body), tt.name)
+	}
+}
+
+// TestResponseJSON method
+// The response keeps the field names API Gateway expects
+func (suite *ResponseSuite) TestResponseJSON() {
+
+	r := NewAuthorizerResponse("jdoe|client", "123456789012")
+	r.AllowMethod(Get, "/jobs/*")
+	r.Context = map[string]interface{}{cognito.ContextUserID: "8f1c-42"}
+
+	body, err := json.Marshal(r.Build())
+	suite.NoError(err)
+	suite.JSONEq(`{
+		"principalId": "jdoe|client",
+		"policyDocument": {
+			"Version": "2012-10-17",
+			"Statement": [{"Action":["execute-api:Invoke"],"Effect":"Allow","Resource":["arn:aws:execute-api:*:123456789012:*/*/GET/jobs/*"]}]
+		},
+		"context": {"userId": "8f1c-42"}
+	}`, string(body))
+}
+
+// TestResponseSuite function
+func TestResponseSuite(t *testing.T) {
+	suite.Run(t, new(ResponseSuite))
+}
+
+// TestHandlerSuite function
+func TestHandlerSuite(t *testing.T) {
+	suite.Run(t, new(HandlerSuite))
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"github.com/pulpfree/univsales-wrksht-pdf/policy"
+)
+
+// applyPolicy allows each rule of the groups, denying everything when none apply
+func applyPolicy(t policy.Table, resp *AuthorizerResponse, groups []string) {
+
+	rules := t.Rules(groups)
+	if len(rules) == 0 {
+		resp.DenyAllMethods()
+		return
+	}
+	for _, r := range rules {
+		verb, _ := parseVerb(r.Verb)
+		resp.AllowMethod(verb, r.Resource)
+	}
+}
+
+// ================================ Helper Functions
+
+// parseVerb returns the verb of a policy rule, which the policy table has already checked
+func parseVerb(s string) (HTTPVerb, bool) {
+	for v := Get; v <= All; v++ {
+		if v.String() == s {
+			return v, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/pulpfree/univsales-wrksht-pdf/policy"
+	"github.com/stretchr/testify/suite"
+)
+
+// PolicySuite struct
+type PolicySuite struct {
+	suite.Suite
+	t policy.Table
+}
+
+// SetupTest method
+func (suite *PolicySuite) SetupTest() {
+	var err error
+	suite.t, err = policy.NewTable(map[string][]string{
+		"admins":     {"* /*"},
+		"installers": {"GET /worksheets/*"},
+		"sales":      {"POST /", "get /jobs/*"},
+	})
+	suite.NoError(err)
+}
+
+// TestApply method
+func (suite *PolicySuite) TestApply() {
+
+	tests := []struct {
+		groups    []string
+		effect    string
+		resources []string
+	}{
+		{[]string{"installers"}, "Allow", []string{"GET/worksheets/*"}},
+		{[]string{"sales", "installers"}, "Allow", []string{"POST/", "GET/jobs/*", "GET/worksheets/*"}},
+		{[]string{"admins"}, "Allow", []string{"*/*"}},
+		{[]string{"contractors"}, "Deny", []string{"*/*"}},
+		{nil, "Deny", []string{"*/*"}},
+	}
+
+	for _, tt := range tests {
+		resp := NewAuthorizerResponse("user|client", "123456789012")
+		resp.Region = "ca-central-1"
+		resp.APIID = "abc123"
+		resp.Stage = "Prod"
+		applyPolicy(suite.t, resp, tt.groups)
+
+		// the rules of the groups are merged into the one statement
+		stmts := resp.Build().PolicyDocument.Statement
+		suite.Len(stmts, 1, tt.groups)
+		suite.Equal(tt.effect, stmts[0].Effect)
+		arns := []string{}
+		for _, r := range tt.resources {
+			arns = append(arns, "arn:aws:execute-api:ca-central-1:123456789012:abc123/Prod/"+r)
+		}
+		suite.Equal(arns, stmts[0].Resource, tt.groups)
+	}
+}
+
+// TestPolicySuite function
+func TestPolicySuite(t *testing.T) {
+	suite.Run(t, new(PolicySuite))
+}
@@ -2,34 +2,174 @@ package awsservices
 
 import (
 	"bytes"
+	"errors"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/pulpfree/univsales-wrksht-pdf/config"
 )
 
-// PutFile function
-func PutFile(fn string, buf *bytes.Buffer, cfg *config.Config) (location string, err error) {
+// PutObject function
+// Uploads the body as a private object in the configured bucket
+func PutObject(key string, body []byte, contentType string, meta map[string]string, cfg *config.Config) error {
 
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String(cfg.AWSRegion),
-	})
+	sess, err := newSession(cfg)
 	if err != nil {
-		return "", err
+		return err
 	}
 
 	uploader := s3manager.NewUploader(sess)
-	res, err := uploader.Upload(&s3manager.UploadInput{
+	_, err = uploader.Upload(&s3manager.UploadInput{
+		ACL:                aws.String(s3.ObjectCannedACLPrivate),
 		Bucket:             aws.String(cfg.S3Bucket),
-		Key:                aws.String(fn),
-		Body:               buf,
-		ContentType:        aws.String("application/pdf"),
+		Key:                aws.String(key),
+		Body:               bytes.NewReader(body),
+		ContentType:        aws.String(contentType),
 		ContentDisposition: aws.String("attachment"),
+		Metadata:           aws.StringMap(meta),
 	})
+
+	return err
+}
+
+// HeadObject function
+// Returns the size and metadata of an existing object
+func HeadObject(key string, cfg *config.Config) (size int64, meta map[string]string, err error) {
+
+	sess, err := newSession(cfg)
 	if err != nil {
-		return "", err
+		return 0, nil, err
 	}
 
-	return string(res.Location), nil
+	res, err := s3.New(sess).HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(cfg.S3Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return aws.Int64Value(res.ContentLength), aws.StringValueMap(res.Metadata), nil
+}
+
+// GetObject function
+// Returns the body of an existing object
+func GetObject(key string, cfg *config.Config) ([]byte, error) {
+
+	sess, err := newSession(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := aws.NewWriteAtBuffer([]byte{})
+	_, err = s3manager.NewDownloader(sess).Download(buf, &s3.GetObjectInput{
+		Bucket: aws.String(cfg.S3Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// ListObjects function
+// Returns the objects with keys starting with prefix
+func ListObjects(prefix string, cfg *config.Config) ([]*s3.Object, error) {
+
+	sess, err := newSession(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	objs := []*s3.Object{}
+	err = s3.New(sess).ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(cfg.S3Bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		objs = append(objs, page.Contents...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return objs, nil
+}
+
+// DeleteObjects function
+// Deletes the objects in batches of the 1000 keys allowed per request
+func DeleteObjects(keys []string, cfg *config.Config) error {
+
+	sess, err := newSession(cfg)
+	if err != nil {
+		return err
+	}
+
+	svc := s3.New(sess)
+	for len(keys) > 0 {
+		n := len(keys)
+		if n > 1000 {
+			n = 1000
+		}
+		ids := make([]*s3.ObjectIdentifier, n)
+		for i, k := range keys[:n] {
+			ids[i] = &s3.ObjectIdentifier{Key: aws.String(k)}
+		}
+		_, err = svc.DeleteObjects(&s3.DeleteObjectsInput{
+			Bucket: aws.String(cfg.S3Bucket),
+			Delete: &s3.Delete{Objects: ids, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return err
+		}
+		keys = keys[n:]
+	}
+
+	return nil
+}
+
+// IsNotFound function
+// Reports whether err is an S3 error for a missing object
+func IsNotFound(err error) bool {
+	var aErr awserr.Error
+	if errors.As(err, &aErr) {
+		return aErr.Code() == "NotFound" || aErr.Code() == s3.ErrCodeNoSuchKey
+	}
+	return false
+}
+
+// GetSignedURL function
+// Returns a url for an existing object that expires after expiry
+func GetSignedURL(key string, expiry time.Duration, cfg *config.Config) (url string, expires time.Time, err error) {
+
+	sess, err := newSession(cfg)
+	if err != nil {
+		return "", expires, err
+	}
+
+	req, _ := s3.New(sess).GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(cfg.S3Bucket),
+		Key:    aws.String(key),
+	})
+
+	expires = time.Now().Add(expiry)
+	url, err = req.Presign(expiry)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return url, expires, nil
+}
+
+// ================================ Helper Functions
+
+func newSession(cfg *config.Config) (*session.Session, error) {
+	return session.NewSession(&aws.Config{
+		Region: aws.String(cfg.AWSRegion),
+	})
 }
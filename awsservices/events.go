@@ -0,0 +1,65 @@
+package awsservices
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/pulpfree/univsales-wrksht-pdf/config"
+)
+
+// PublishMessage function
+// Publishes to an SNS topic, the event type is added as a message attribute for subscription filters
+func PublishMessage(topicARN, eventType, body string, cfg *config.Config) error {
+
+	sess, err := newSession(cfg)
+	if err != nil {
+		return err
+	}
+
+	_, err = sns.New(sess).Publish(&sns.PublishInput{
+		Message: aws.String(body),
+		MessageAttributes: map[string]*sns.MessageAttributeValue{
+			"type": {
+				DataType:    aws.String("String"),
+				StringValue: aws.String(eventType),
+			},
+		},
+		TopicArn: aws.String(topicARN),
+	})
+
+	return err
+}
+
+// PutEvent function
+// Sends a single event to an EventBridge bus
+func PutEvent(busName, source, detailType, detail string, cfg *config.Config) error {
+
+	sess, err := newSession(cfg)
+	if err != nil {
+		return err
+	}
+
+	res, err := eventbridge.New(sess).PutEvents(&eventbridge.PutEventsInput{
+		Entries: []*eventbridge.PutEventsRequestEntry{
+			{
+				Detail:       aws.String(detail),
+				DetailType:   aws.String(detailType),
+				EventBusName: aws.String(busName),
+				Source:       aws.String(source),
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// PutEvents reports rejected entries in the response rather than as an error
+	if aws.Int64Value(res.FailedEntryCount) > 0 {
+		e := res.Entries[0]
+		return fmt.Errorf("Event rejected: %s %s", aws.StringValue(e.ErrorCode), aws.StringValue(e.ErrorMessage))
+	}
+
+	return nil
+}
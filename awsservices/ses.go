@@ -0,0 +1,25 @@
+package awsservices
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ses"
+	"github.com/pulpfree/univsales-wrksht-pdf/config"
+)
+
+// SendRawEmail function
+// Sends a complete MIME message, required for messages with attachments
+func SendRawEmail(from string, to []string, raw []byte, cfg *config.Config) error {
+
+	sess, err := newSession(cfg)
+	if err != nil {
+		return err
+	}
+
+	_, err = ses.New(sess).SendRawEmail(&ses.SendRawEmailInput{
+		Destinations: aws.StringSlice(to),
+		RawMessage:   &ses.RawMessage{Data: raw},
+		Source:       aws.String(from),
+	})
+
+	return err
+}
@@ -0,0 +1,23 @@
+package awsservices
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/pulpfree/univsales-wrksht-pdf/config"
+)
+
+// SendMessage function
+func SendMessage(queueURL, body string, cfg *config.Config) error {
+
+	sess, err := newSession(cfg)
+	if err != nil {
+		return err
+	}
+
+	_, err = sqs.New(sess).SendMessage(&sqs.SendMessageInput{
+		MessageBody: aws.String(body),
+		QueueUrl:    aws.String(queueURL),
+	})
+
+	return err
+}
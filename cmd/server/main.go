@@ -0,0 +1,281 @@
+// Command server serves the worksheet api over net/http for local development,
+// translating each request into the api gateway proxy request the lambda handler receives.
+//
+// Example, using the local test database and writing files to ./tmp:
+//
+//	go run ./cmd/server -stage test -out ./tmp -no-auth
+package main
+
+import (
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+	"github.com/pulpfree/univsales-wrksht-pdf/access"
+	"github.com/pulpfree/univsales-wrksht-pdf/api"
+	"github.com/pulpfree/univsales-wrksht-pdf/cognito"
+	"github.com/pulpfree/univsales-wrksht-pdf/config"
+	"github.com/pulpfree/univsales-wrksht-pdf/logger"
+	"github.com/pulpfree/univsales-wrksht-pdf/metrics"
+	"github.com/pulpfree/univsales-wrksht-pdf/policy"
+	"github.com/pulpfree/univsales-wrksht-pdf/tracing"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	filesPath   = "/files/"
+	jobsPath    = "/jobs/"
+	maxBodySize = 1 << 20
+	metricsPath = "/metrics"
+	wrkshtsPath = "/worksheets/"
+)
+
+type server struct {
+	cfg             *config.Config
+	handler         *api.Handler
+	noAuth          bool
+	policies        policy.Table
+	servicePolicies policy.Table
+	tokens          cognito.Validator
+}
+
+func main() {
+
+	addr := flag.String("addr", ":3000", "address to listen on")
+	defaults := flag.String("defaults", "config/defaults.yml", "path to the config defaults file")
+	envFile := flag.String("env", ".env", "path to a local file of KEY=value config, such as secrets, read by the dev and test stages. Environment variables take precedence")
+	eventsFile := flag.String("events", "", "append published events to this file as JSON lines")
+	noAuth := flag.Bool("no-auth", false, "skip Cognito token validation, for development only")
+	out := flag.String("out", "", "write files to this local directory instead of S3")
+	smtpAddr := flag.String("smtp", "", "send email through this SMTP server instead of SES, such as localhost:1025")
+	stage := flag.String("stage", string(config.TestEnv), "config stage environment")
+	flag.Parse()
+
+	os.Setenv("Stage", *stage)
+	cfg := &config.Config{DefaultsFilePath: *defaults, EnvFilePath: *envFile}
+	if err := cfg.Load(); err != nil {
+		log.Fatal(err)
+	}
+	if err := logger.Configure(cfg); err != nil {
+		log.Fatal(err)
+	}
+	// measurements are scraped from /metrics rather than written as log lines
+	cfg.Metrics = metrics.SinkPrometheus
+	if err := metrics.Configure(cfg); err != nil {
+		log.Fatal(err)
+	}
+	if err := tracing.Configure(cfg); err != nil {
+		log.Fatal(err)
+	}
+
+	if *eventsFile != "" {
+		cfg.EventBusName = ""
+		cfg.EventTopicARN = ""
+		cfg.EventsFile = *eventsFile
+	}
+
+	if *smtpAddr != "" {
+		cfg.SMTPAddr = *smtpAddr
+	}
+
+	mux := http.NewServeMux()
+	if *out != "" {
+		cfg.OutputDir = *out
+		cfg.OutputURL = "http://" + hostAddr(*addr) + filesPath
+		mux.Handle(filesPath, http.StripPrefix(filesPath, http.FileServer(http.Dir(*out))))
+	}
+
+	s := &server{
+		cfg:     cfg,
+		handler: api.NewHandler(cfg),
+		noAuth:  *noAuth,
+	}
+	if !*noAuth {
+		pool, err := cognito.NewSettings(cfg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		s.tokens = cognito.NewValidator(pool, cognito.NewJWKS(pool.JWKSURL(), cognito.DefaultJWKSTTL))
+		if s.policies, err = policy.NewTable(cfg.AuthPolicies); err != nil {
+			log.Fatal(err)
+		}
+		if s.servicePolicies, err = policy.NewTable(cfg.ServicePolicies); err != nil {
+			log.Fatal(err)
+		}
+	}
+	mux.HandleFunc(api.RootResource, s.serve)
+	mux.HandleFunc(api.BatchResource, s.serve)
+	mux.HandleFunc(api.HealthResource, s.serve)
+	mux.HandleFunc(jobsPath, s.serve)
+	mux.HandleFunc(wrkshtsPath, s.serve)
+	mux.Handle(metricsPath, metrics.Handler())
+
+	if *noAuth {
+		log.Warn("Cognito authorization is disabled")
+	}
+	log.Infof("Listening on %s with stage: %s", *addr, cfg.GetStageEnv())
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
+
+func (s *server) serve(w http.ResponseWriter, r *http.Request) {
+
+	req, err := s.proxyRequest(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Resource == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Mirror the api gateway setup, where only the preflights, ping and health routes skip the authorizer,
+	// and the authorizer policy of the caller's groups decides the routes it may call
+	if r.Method != http.MethodOptions && (r.Method != http.MethodGet || (req.Resource != api.RootResource && req.Resource != api.HealthResource)) {
+		principalID, id, err := s.authorize(r)
+		if err != nil {
+			log.Errorf("Error in token validation: %s", err)
+			http.Error(w, `{"message":"Unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		if !s.allowed(id, r) {
+			log.Errorf("Policy of %s denies %s %s", principalID, r.Method, r.URL.Path)
+			http.Error(w, `{"message":"User is not authorized to access this resource"}`, http.StatusForbidden)
+			return
+		}
+		req.RequestContext.Authorizer = id.Context()
+		req.RequestContext.Authorizer["principalId"] = principalID
+	}
+
+	res, err := s.handler.HandleRequest(r.Context(), req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	writeResponse(w, res)
+}
+
+// proxyRequest translates the http request, leaving Resource empty for unknown routes
+func (s *server) proxyRequest(w http.ResponseWriter, r *http.Request) (events.APIGatewayProxyRequest, error) {
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		return events.APIGatewayProxyRequest{}, err
+	}
+
+	req := events.APIGatewayProxyRequest{
+		Body:                            string(body),
+		Headers:                         map[string]string{},
+		HTTPMethod:                      r.Method,
+		MultiValueHeaders:               map[string][]string(r.Header),
+		MultiValueQueryStringParameters: map[string][]string(r.URL.Query()),
+		Path:                            r.URL.Path,
+		QueryStringParameters:           map[string]string{},
+		RequestContext: events.APIGatewayProxyRequestContext{
+			HTTPMethod: r.Method,
+			RequestID:  uuid.New().String(),
+			Stage:      string(s.cfg.GetStageEnv()),
+		},
+	}
+	for k := range r.Header {
+		req.Headers[k] = r.Header.Get(k)
+	}
+	for k := range r.URL.Query() {
+		req.QueryStringParameters[k] = r.URL.Query().Get(k)
+	}
+
+	switch {
+	case r.URL.Path == api.RootResource:
+		req.Resource = api.RootResource
+	case r.URL.Path == api.BatchResource:
+		req.Resource = api.BatchResource
+	case r.URL.Path == api.HealthResource:
+		req.Resource = api.HealthResource
+	case strings.HasPrefix(r.URL.Path, jobsPath) && len(r.URL.Path) > len(jobsPath):
+		req.Resource = api.JobResource
+		req.PathParameters = map[string]string{"id": strings.TrimPrefix(r.URL.Path, jobsPath)}
+	case strings.HasPrefix(r.URL.Path, wrkshtsPath) && strings.HasSuffix(r.URL.Path, "/prune"):
+		req.Resource = api.PruneResource
+		req.PathParameters = map[string]string{"number": strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, wrkshtsPath), "/prune")}
+	case strings.HasPrefix(r.URL.Path, wrkshtsPath) && len(r.URL.Path) > len(wrkshtsPath):
+		req.Resource = api.WrkshtResource
+		req.PathParameters = map[string]string{"number": strings.TrimPrefix(r.URL.Path, wrkshtsPath)}
+	}
+
+	return req, nil
+}
+
+// authorize validates the bearer token the same way the lambda authorizer does,
+// returning the principal and the identity the authorizer passes in its context
+func (s *server) authorize(r *http.Request) (string, *cognito.Identity, error) {
+
+	if s.noAuth {
+		id := access.Local()
+		id.Name = "Local Developer"
+		return "local|dev", id, nil
+	}
+
+	token := r.Header.Get("Authorization")
+	if token == "" {
+		return "", nil, fmt.Errorf("missing Authorization header")
+	}
+
+	t, err := s.tokens.Validate(strings.TrimPrefix(token, "Bearer "))
+	if err != nil {
+		return "", nil, err
+	}
+
+	return t.Principal, t.Identity, nil
+}
+
+// allowed applies the policy table as the lambda authorizer does, services have a policy
+// of their own and people get the policies of their groups
+func (s *server) allowed(id *cognito.Identity, r *http.Request) bool {
+
+	if s.noAuth {
+		return true
+	}
+	if id.Caller == cognito.CallerService {
+		return s.servicePolicies.Allows([]string{id.Name}, r.Method, r.URL.Path)
+	}
+
+	return s.policies.Allows(id.Groups, r.Method, r.URL.Path)
+}
+
+func writeResponse(w http.ResponseWriter, res events.APIGatewayProxyResponse) {
+
+	for k, v := range res.Headers {
+		w.Header().Set(k, v)
+	}
+	for k, vs := range res.MultiValueHeaders {
+		for _, v := range vs {
+			w.Header().Add(k, v)
+		}
+	}
+
+	body := []byte(res.Body)
+	if res.IsBase64Encoded {
+		var err error
+		body, err = base64.StdEncoding.DecodeString(res.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(res.StatusCode)
+	w.Write(body)
+}
+
+func hostAddr(addr string) string {
+	if strings.HasPrefix(addr, ":") {
+		return "localhost" + addr
+	}
+	return addr
+}
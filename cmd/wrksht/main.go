@@ -0,0 +1,343 @@
+// Command wrksht renders worksheets without going through the api.
+//
+// Usage:
+//
+//	wrksht render   -quote <id> | -number <n> [-o file]
+//	wrksht fixture  -f quote.json [-o file]
+//	wrksht upload   -quote <id> | -number <n> [-out dir] [-force]
+//	wrksht validate -quote <id> | -number <n> | -f quote.json
+//	wrksht prune    [-number <n>] [-keep n] [-out dir]
+//
+// Every subcommand also accepts -stage, -defaults and -env to select the configuration.
+// The test stage runs without AWS, reading only the defaults, environment and env file.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/pulpfree/univsales-wrksht-pdf/access"
+	"github.com/pulpfree/univsales-wrksht-pdf/config"
+	"github.com/pulpfree/univsales-wrksht-pdf/model"
+	"github.com/pulpfree/univsales-wrksht-pdf/model/mongo"
+	"github.com/pulpfree/univsales-wrksht-pdf/pdf"
+	"github.com/pulpfree/univsales-wrksht-pdf/service"
+)
+
+const usage = `usage: wrksht <command> [flags]
+
+commands:
+  render    render a worksheet from the database to a local file
+  fixture   render a worksheet from a JSON quote fixture, without the database
+  upload    render a worksheet from the database and store it
+  validate  print warnings for quote data that will not render as expected
+  prune     delete old worksheet versions beyond the retention limit
+
+run "wrksht <command> -h" for the command flags
+`
+
+// options shared by the subcommands
+type options struct {
+	defaults string
+	envFile  string
+	fixture  string
+	force    bool
+	keep     int
+	number   int
+	out      string
+	output   string
+	quoteID  string
+	stage    string
+}
+
+func main() {
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cmd := os.Args[1]
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	opts := &options{}
+	fs.StringVar(&opts.defaults, "defaults", "config/defaults.yml", "path to the config defaults file")
+	fs.StringVar(&opts.envFile, "env", ".env", "path to a local file of KEY=value config, read by the dev and test stages. Environment variables take precedence")
+	fs.StringVar(&opts.stage, "stage", "", "config stage environment, defaults to the Stage env var or defaults file")
+
+	var run func(*options) error
+	switch cmd {
+	case "render":
+		quoteFlags(fs, opts)
+		fs.StringVar(&opts.output, "o", "", "output file, defaults to the worksheet file name")
+		run = render
+	case "fixture":
+		fs.StringVar(&opts.fixture, "f", "", "JSON quote fixture file")
+		fs.StringVar(&opts.output, "o", "", "output file, defaults to the worksheet file name")
+		run = fixture
+	case "upload":
+		quoteFlags(fs, opts)
+		fs.StringVar(&opts.out, "out", "", "store in this local directory instead of S3")
+		fs.BoolVar(&opts.force, "force", false, "render again even when the stored worksheet is unchanged")
+		run = upload
+	case "validate":
+		quoteFlags(fs, opts)
+		fs.StringVar(&opts.fixture, "f", "", "JSON quote fixture file")
+		run = validate
+	case "prune":
+		fs.IntVar(&opts.number, "number", 0, "quote number, defaults to every quote")
+		fs.IntVar(&opts.keep, "keep", -1, "versions to keep per quote, defaults to the RetainVersions config")
+		fs.StringVar(&opts.out, "out", "", "prune this local directory instead of S3")
+		run = prune
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	fs.Parse(os.Args[2:])
+
+	if err := run(opts); err != nil {
+		fmt.Fprintf(os.Stderr, "wrksht %s: %s\n", cmd, err)
+		os.Exit(1)
+	}
+}
+
+func quoteFlags(fs *flag.FlagSet, opts *options) {
+	fs.StringVar(&opts.quoteID, "quote", "", "quote id")
+	fs.IntVar(&opts.number, "number", 0, "quote number, uses the latest revision")
+}
+
+// ================================ Commands
+
+func render(opts *options) error {
+
+	cfg, db, err := connect(opts)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	quoteID, err := resolveQuoteID(opts, db)
+	if err != nil {
+		return err
+	}
+
+	p, err := service.New(cfg, db).Render(&pdf.Request{Identity: access.Local(), QuoteID: quoteID})
+	if err != nil {
+		return err
+	}
+
+	return writeFile(p, opts.output)
+}
+
+func fixture(opts *options) error {
+
+	cfg, err := loadConfig(opts)
+	if err != nil {
+		return err
+	}
+
+	q, err := readFixture(opts.fixture)
+	if err != nil {
+		return err
+	}
+
+	// fixtures are often hand written, so they are checked as validate does before the render
+	warnings := pdf.Validate(q)
+	for _, w := range warnings {
+		fmt.Fprintln(os.Stderr, w)
+	}
+	if err := renderable(warnings); err != nil {
+		return err
+	}
+
+	p, err := service.New(cfg, nil).RenderQuote(&pdf.Request{Identity: access.Local(), QuoteID: q.ID.Hex()}, q)
+	if err != nil {
+		return err
+	}
+
+	return writeFile(p, opts.output)
+}
+
+func upload(opts *options) error {
+
+	cfg, db, err := connect(opts)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if opts.out != "" {
+		cfg.OutputDir = opts.out
+	}
+
+	quoteID, err := resolveQuoteID(opts, db)
+	if err != nil {
+		return err
+	}
+
+	file, err := service.New(cfg, db).Create(&pdf.Request{Force: opts.force, Identity: access.Local(), QuoteID: quoteID})
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(os.Stdout).Encode(file)
+}
+
+func validate(opts *options) error {
+
+	var q *model.Quote
+	if opts.fixture != "" {
+		var err error
+		if q, err = readFixture(opts.fixture); err != nil {
+			return err
+		}
+	} else {
+		_, db, err := connect(opts)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		quoteID, err := resolveQuoteID(opts, db)
+		if err != nil {
+			return err
+		}
+		if q, err = db.FetchQuote(quoteID); err != nil {
+			return err
+		}
+	}
+
+	warnings := pdf.Validate(q)
+	for _, w := range warnings {
+		fmt.Println(w)
+	}
+	if err := renderable(warnings); err != nil {
+		return err
+	}
+	if len(warnings) == 0 {
+		fmt.Printf("quote %d has no warnings\n", q.Number)
+	}
+
+	return nil
+}
+
+func prune(opts *options) error {
+
+	cfg, err := loadConfig(opts)
+	if err != nil {
+		return err
+	}
+	if opts.out != "" {
+		cfg.OutputDir = opts.out
+	}
+	if opts.keep >= 0 {
+		cfg.RetainVersions = opts.keep
+	}
+
+	// pruning only touches storage, so no database connection is needed
+	svc := service.New(cfg, nil)
+
+	var deleted []string
+	if opts.number != 0 {
+		deleted, err = svc.Prune(opts.number)
+	} else {
+		deleted, err = svc.PruneAll()
+	}
+	for _, k := range deleted {
+		fmt.Println("deleted", k)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("pruned %d worksheet versions\n", len(deleted))
+
+	return nil
+}
+
+// ================================ Helper Functions
+
+func loadConfig(opts *options) (*config.Config, error) {
+	if opts.stage != "" {
+		os.Setenv("Stage", opts.stage)
+	}
+	cfg := &config.Config{DefaultsFilePath: opts.defaults, EnvFilePath: opts.envFile}
+	return cfg, cfg.Load()
+}
+
+func connect(opts *options) (*config.Config, model.DBHandler, error) {
+
+	cfg, err := loadConfig(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	db, err := mongo.NewDB(cfg.GetMongoConnectURL(), cfg.DBName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cfg, db, nil
+}
+
+func resolveQuoteID(opts *options, db model.DBHandler) (string, error) {
+	switch {
+	case opts.quoteID != "" && opts.number != 0:
+		return "", errors.New("use only one of -quote or -number")
+	case opts.quoteID != "":
+		return opts.quoteID, nil
+	case opts.number != 0:
+		return db.FetchQuoteIDByNumber(opts.number)
+	}
+	return "", errors.New("either -quote or -number is required")
+}
+
+func readFixture(fp string) (*model.Quote, error) {
+
+	if fp == "" {
+		return nil, errors.New("-f fixture file is required")
+	}
+
+	file, err := ioutil.ReadFile(fp)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &model.Quote{}
+	if err := json.Unmarshal(file, q); err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: %s", fp, err)
+	}
+
+	return q, nil
+}
+
+// renderable returns an error when any of the Validate warnings would stop the render
+func renderable(warnings []string) error {
+	for _, w := range warnings {
+		if strings.HasPrefix(w, "error:") {
+			return errors.New("quote will not render")
+		}
+	}
+	return nil
+}
+
+func writeFile(p *pdf.PDF, fp string) error {
+
+	if fp == "" {
+		fp = p.FileName()
+	}
+
+	body, err := p.Bytes()
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(fp, body, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %s (%d bytes)\n", fp, len(body))
+
+	return nil
+}
@@ -0,0 +1,275 @@
+package cognito
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pulpfree/univsales-wrksht-pdf/config"
+)
+
+// Claim name constants
+const (
+	// BranchClaim is the custom attribute holding the branches the user works in, comma separated
+	BranchClaim = "custom:branch"
+	// GroupsClaim is the claim listing the user's Cognito groups
+	GroupsClaim = "cognito:groups"
+	// RoleClaim is the custom attribute holding the user's role, such as manager or rep
+	RoleClaim = "custom:role"
+)
+
+// Caller constants, distinguishing people from automated callers in the authorizer context.
+// CallerLocal is never set by the authorizer, only by the local tools
+const (
+	CallerLocal   = "local"
+	CallerService = "service"
+	CallerUser    = "user"
+)
+
+// Token use constants, the token_use claim values
+const (
+	TokenUseAccess = "access"
+	TokenUseID     = "id"
+)
+
+// Errors returned for tokens that validate against the pool keys but are not meant for this api
+var (
+	ErrAudience = errors.New("token issued for another app client")
+	ErrIssuer   = errors.New("token issued by another user pool")
+	ErrTokenUse = errors.New("token use not accepted")
+)
+
+// poolIDRe matches a user pool id, such as ca-central-1_1DQjnU6jd, capturing the region
+var poolIDRe = regexp.MustCompile(`^([a-z]{2}(?:-[a-z]+)+-\d)_[0-9A-Za-z]+$`)
+
+// Settings struct
+// The user pool and app clients whose tokens are accepted. Services maps the app clients of
+// automated callers, which use the client credentials grant, to the service names.
+// TokenUse lists the tokens accepted from users. Id tokens carry the custom:branch and custom:role
+// attributes the quote checks need, access tokens only carry them when a pre token generation trigger adds them
+type Settings struct {
+	ClientIDs []string
+	PoolID    string
+	Region    string
+	Services  map[string]string
+	TokenUse  []string
+}
+
+// NewSettings function
+// Reads and validates the Cognito config, called once at cold start so a bad config fails the deploy
+// rather than each request
+func NewSettings(cfg *config.Config) (*Settings, error) {
+
+	s := &Settings{
+		ClientIDs: cfg.CognitoClientIDs,
+		PoolID:    cfg.CognitoPoolID,
+		Region:    cfg.CognitoRegion,
+		Services:  map[string]string{},
+		TokenUse:  cfg.CognitoTokenUse,
+	}
+	for name, clientID := range cfg.ServiceClients {
+		if contains(s.ClientIDs, clientID) {
+			return nil, fmt.Errorf("Service %s uses the app client of users: %s", name, clientID)
+		}
+		if other, ok := s.Services[clientID]; ok {
+			return nil, fmt.Errorf("Services %s and %s share the app client %s", name, other, clientID)
+		}
+		s.Services[clientID] = name
+	}
+
+	m := poolIDRe.FindStringSubmatch(s.PoolID)
+	if m == nil {
+		return nil, fmt.Errorf("Invalid CognitoPoolID value: %s", s.PoolID)
+	}
+	if s.Region != m[1] {
+		return nil, fmt.Errorf("CognitoRegion %s does not match the region of pool %s", s.Region, s.PoolID)
+	}
+	if len(s.ClientIDs) == 0 {
+		return nil, errors.New("Missing CognitoClientID value")
+	}
+	if len(s.TokenUse) == 0 {
+		return nil, errors.New("Missing CognitoTokenUse value")
+	}
+	for _, u := range s.TokenUse {
+		if u != TokenUseAccess && u != TokenUseID {
+			return nil, fmt.Errorf("Invalid CognitoTokenUse value: %s", u)
+		}
+	}
+
+	return s, nil
+}
+
+// Issuer method
+func (s *Settings) Issuer() string {
+	return fmt.Sprintf("https://cognito-idp.%s.amazonaws.com/%s", s.Region, s.PoolID)
+}
+
+// JWKSURL method
+func (s *Settings) JWKSURL() string {
+	return s.Issuer() + "/.well-known/jwks.json"
+}
+
+// Service method
+// Returns the service name when the claims are of a client credentials token of a service app client
+func (s *Settings) Service(claims map[string]interface{}) (string, bool) {
+	if claimString(claims, "token_use") != TokenUseAccess {
+		return "", false
+	}
+	name, ok := s.Services[claimString(claims, "client_id")]
+	return name, ok
+}
+
+// CheckClaims method
+// Checks the claims of a token with a valid signature were issued by the pool, for one of the
+// app clients and for an accepted use. Access tokens carry the app client in client_id, id tokens in aud.
+// Service app clients only issue client credentials tokens, which have no user and are accepted whatever TokenUse lists
+func (s *Settings) CheckClaims(claims map[string]interface{}) error {
+
+	if iss, _ := claims["iss"].(string); iss != s.Issuer() {
+		return ErrIssuer
+	}
+
+	if _, ok := s.Service(claims); ok {
+		if claimString(claims, "username") != "" {
+			return fmt.Errorf("%w: user token from service client %q", ErrAudience, claimString(claims, "client_id"))
+		}
+		return nil
+	}
+
+	use, _ := claims["token_use"].(string)
+	if !contains(s.TokenUse, use) {
+		return fmt.Errorf("%w: %q", ErrTokenUse, use)
+	}
+
+	client, _ := claims["client_id"].(string)
+	if use == TokenUseID {
+		client, _ = claims["aud"].(string)
+	}
+	if !contains(s.ClientIDs, client) {
+		return fmt.Errorf("%w: %q", ErrAudience, client)
+	}
+
+	return nil
+}
+
+// Identity struct
+// The authenticated user, passed from the authorizer to the handler in the authorizer context
+type Identity struct {
+	Branches []string `json:"branches,omitempty"`
+	Caller   string   `json:"caller,omitempty"`
+	Email    string   `json:"email,omitempty"`
+	Groups   []string `json:"groups,omitempty"`
+	Name     string   `json:"name,omitempty"`
+	Role     string   `json:"role,omitempty"`
+	UserID   string   `json:"userID"`
+}
+
+// Authorizer context key constants
+const (
+	ContextBranches = "branches"
+	ContextCaller   = "caller"
+	ContextEmail    = "email"
+	ContextGroups   = "groups"
+	ContextName     = "name"
+	ContextRole     = "role"
+	ContextUserID   = "userId"
+)
+
+// IdentityFromClaims function
+// Access tokens carry no email, name or custom attribute claims, so the identity has no branches or role
+// and the user name stands in for the name
+func IdentityFromClaims(claims map[string]interface{}) *Identity {
+
+	id := &Identity{
+		Branches: splitList(claimString(claims, BranchClaim)),
+		Caller:   CallerUser,
+		Email:    claimString(claims, "email"),
+		Name:     claimString(claims, "name"),
+		Role:     claimString(claims, RoleClaim),
+		UserID:   claimString(claims, "sub"),
+	}
+	if id.Name == "" {
+		id.Name = strings.TrimSpace(claimString(claims, "given_name") + " " + claimString(claims, "family_name"))
+	}
+	if id.Name == "" {
+		id.Name = claimString(claims, "username")
+	}
+	if id.Name == "" {
+		id.Name = claimString(claims, "cognito:username")
+	}
+
+	list, _ := claims[GroupsClaim].([]interface{})
+	for _, v := range list {
+		if g, ok := v.(string); ok {
+			id.Groups = append(id.Groups, g)
+		}
+	}
+
+	return id
+}
+
+// IdentityFromContext function
+// Returns nil when the authorizer set no user, as for the routes without authorization
+func IdentityFromContext(ctx map[string]interface{}) *Identity {
+
+	userID, _ := ctx[ContextUserID].(string)
+	if userID == "" {
+		return nil
+	}
+
+	id := &Identity{UserID: userID}
+	id.Caller, _ = ctx[ContextCaller].(string)
+	id.Email, _ = ctx[ContextEmail].(string)
+	id.Name, _ = ctx[ContextName].(string)
+	id.Role, _ = ctx[ContextRole].(string)
+	if bs, _ := ctx[ContextBranches].(string); bs != "" {
+		id.Branches = strings.Split(bs, ",")
+	}
+	if gs, _ := ctx[ContextGroups].(string); gs != "" {
+		id.Groups = strings.Split(gs, ",")
+	}
+
+	return id
+}
+
+// Context method
+// Returns the identity as authorizer context, which only holds strings, numbers and booleans
+func (id *Identity) Context() map[string]interface{} {
+	return map[string]interface{}{
+		ContextBranches: strings.Join(id.Branches, ","),
+		ContextCaller:   id.Caller,
+		ContextEmail:    id.Email,
+		ContextGroups:   strings.Join(id.Groups, ","),
+		ContextName:     id.Name,
+		ContextRole:     id.Role,
+		ContextUserID:   id.UserID,
+	}
+}
+
+// ================================ Helper Functions
+
+func claimString(claims map[string]interface{}, key string) string {
+	s, _ := claims[key].(string)
+	return s
+}
+
+// splitList splits a comma separated attribute, dropping the blanks
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s && s != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package cognito
+
+import (
+	"errors"
+	"testing"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+	"github.com/pulpfree/univsales-wrksht-pdf/cognito/cognitotest"
+	"github.com/pulpfree/univsales-wrksht-pdf/config"
+	"github.com/stretchr/testify/suite"
+)
+
+// CognitoSuite struct
+type CognitoSuite struct {
+	suite.Suite
+	cfg *config.Config
+	s   *Settings
+}
+
+// SetupTest method
+func (suite *CognitoSuite) SetupTest() {
+	suite.cfg = &config.Config{}
+	suite.cfg.CognitoClientIDs = []string{cognitotest.ClientID}
+	suite.cfg.CognitoPoolID = cognitotest.PoolID
+	suite.cfg.CognitoRegion = cognitotest.Region
+	suite.cfg.CognitoTokenUse = []string{TokenUseAccess}
+
+	var err error
+	suite.s, err = NewSettings(suite.cfg)
+	suite.NoError(err)
+}
+
+// TestNewSettings method
+func (suite *CognitoSuite) TestNewSettings() {
+
+	suite.Equal("https://cognito-idp.ca-central-1.amazonaws.com/ca-central-1_1DQjnU6jd/.well-known/jwks.json", suite.s.JWKSURL())
+
+	tests := []func(c *config.Config){
+		func(c *config.Config) { c.CognitoPoolID = "1DQjnU6jd" },
+		func(c *config.Config) { c.CognitoRegion = "us-east-1" },
+		func(c *config.Config) { c.CognitoClientIDs = nil },
+		func(c *config.Config) { c.CognitoTokenUse = nil },
+		func(c *config.Config) { c.CognitoTokenUse = []string{"refresh"} },
+		func(c *config.Config) { c.ServiceClients = map[string]string{"scheduler": cognitotest.ClientID} },
+		func(c *config.Config) { c.ServiceClients = map[string]string{"scheduler": "svc", "reports": "svc"} },
+	}
+	for i, change := range tests {
+		suite.SetupTest()
+		change(suite.cfg)
+		_, err := NewSettings(suite.cfg)
+		suite.Error(err, i)
+	}
+}
+
+// TestCheckClaims method
+func (suite *CognitoSuite) TestCheckClaims() {
+
+	suite.NoError(suite.s.CheckClaims(cognitotest.Claims(nil)))
+
+	tests := []struct {
+		changes jwt.MapClaims
+		err     error
+	}{
+		{jwt.MapClaims{"client_id": "otherclient"}, ErrAudience},
+		{jwt.MapClaims{"client_id": nil}, ErrAudience},
+		{jwt.MapClaims{"iss": "https://cognito-idp.ca-central-1.amazonaws.com/ca-central-1_other"}, ErrIssuer},
+		{jwt.MapClaims{"token_use": TokenUseID, "aud": cognitotest.ClientID}, ErrTokenUse},
+	}
+	for _, tt := range tests {
+		err := suite.s.CheckClaims(cognitotest.Claims(tt.changes))
+		suite.True(errors.Is(err, tt.err), tt.changes)
+	}
+
+	// id tokens name the app client in aud
+	suite.s.TokenUse = []string{TokenUseAccess, TokenUseID}
+	suite.NoError(suite.s.CheckClaims(cognitotest.Claims(jwt.MapClaims{"token_use": TokenUseID, "aud": cognitotest.ClientID, "client_id": nil})))
+	suite.True(errors.Is(suite.s.CheckClaims(cognitotest.Claims(jwt.MapClaims{"token_use": TokenUseID, "aud": "otherclient"})), ErrAudience))
+
+	// service tokens are access tokens, accepted when users must send id tokens
+	suite.s.Services = map[string]string{"svcclient": "scheduler"}
+	suite.s.TokenUse = []string{TokenUseID}
+	suite.NoError(suite.s.CheckClaims(cognitotest.ServiceClaims("svcclient", nil)))
+	suite.True(errors.Is(suite.s.CheckClaims(cognitotest.Claims(nil)), ErrTokenUse))
+}
+
+// TestIdentity method
+func (suite *CognitoSuite) TestIdentity() {
+
+	id := IdentityFromClaims(map[string]interface{}{
+		"email":       "jane@universalwindows.ca",
+		"given_name":  "Jane",
+		"family_name": "Doe",
+		"sub":         "8f1c-42",
+		BranchClaim:   "hamilton, burlington",
+		GroupsClaim:   []interface{}{"sales", "installers"},
+		RoleClaim:     "manager",
+	})
+	suite.Equal(&Identity{
+		Branches: []string{"hamilton", "burlington"},
+		Caller:   CallerUser,
+		Email:    "jane@universalwindows.ca",
+		Groups:   []string{"sales", "installers"},
+		Name:     "Jane Doe",
+		Role:     "manager",
+		UserID:   "8f1c-42",
+	}, id)
+
+	// the authorizer context round trip, with the principal id api gateway adds
+	ctx := id.Context()
+	ctx["principalId"] = "jane|client"
+	suite.Equal(id, IdentityFromContext(ctx))
+
+	// access tokens have only the user name
+	id = IdentityFromClaims(map[string]interface{}{"sub": "8f1c-42", "username": "jdoe"})
+	suite.Equal("jdoe", id.Name)
+	suite.Nil(id.Branches)
+	suite.Nil(id.Groups)
+
+	suite.Nil(IdentityFromContext(map[string]interface{}{"principalId": "jane|client"}))
+	suite.Nil(IdentityFromContext(nil))
+}
+
+// TestCognitoSuite function
+func TestCognitoSuite(t *testing.T) {
+	suite.Run(t, new(CognitoSuite))
+}
@@ -0,0 +1,96 @@
+// Package cognitotest provides the Cognito token fixtures shared by the cognito and authorizer tests.
+// It does not import the cognito package, so the cognito package tests can use it
+package cognitotest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+// Fixture constants, a user pool with one user app client
+const (
+	ClientID = "4k1q2b3c4d5e6f7g8h9i0j"
+	Issuer   = "https://cognito-idp." + Region + ".amazonaws.com/" + PoolID
+	KeyID    = "k1"
+	PoolID   = "ca-central-1_1DQjnU6jd"
+	Region   = "ca-central-1"
+	UserID   = "8f1c-42"
+	Username = "jdoe"
+)
+
+// NewKey function
+// Returns a key to sign tokens with, a test signs its own tokens rather than using Cognito keys
+func NewKey() (*rsa.PrivateKey, error) {
+	return rsa.GenerateKey(rand.Reader, 2048)
+}
+
+// Claims function
+// Returns the claims of an access token for a user in the sales group, valid for an hour,
+// with changes applied. A nil change removes the claim
+func Claims(changes jwt.MapClaims) jwt.MapClaims {
+	return apply(jwt.MapClaims{
+		"client_id":      ClientID,
+		"cognito:groups": []string{"sales"},
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"iss":            Issuer,
+		"sub":            UserID,
+		"token_use":      "access",
+		"username":       Username,
+	}, changes)
+}
+
+// IDClaims function
+// Returns the claims of an id token for a manager of the hamilton branch in the sales group,
+// valid for an hour, with changes applied as for Claims
+func IDClaims(changes jwt.MapClaims) jwt.MapClaims {
+	return apply(jwt.MapClaims{
+		"aud":              ClientID,
+		"cognito:groups":   []string{"sales"},
+		"cognito:username": Username,
+		"custom:branch":    "hamilton",
+		"custom:role":      "manager",
+		"email":            "jdoe@universalwindows.ca",
+		"exp":              time.Now().Add(time.Hour).Unix(),
+		"iss":              Issuer,
+		"sub":              UserID,
+		"token_use":        "id",
+	}, changes)
+}
+
+// ServiceClaims function
+// Returns the claims of a client credentials access token for the app client, valid for an hour,
+// with changes applied as for Claims
+func ServiceClaims(clientID string, changes jwt.MapClaims) jwt.MapClaims {
+	return apply(jwt.MapClaims{
+		"client_id": clientID,
+		"exp":       time.Now().Add(time.Hour).Unix(),
+		"iss":       Issuer,
+		"scope":     "wrksht/generate",
+		"sub":       clientID,
+		"token_use": "access",
+	}, changes)
+}
+
+// Sign function
+// Signs the claims with RS256, naming the key kid in the header
+func Sign(claims jwt.MapClaims, kid string, key *rsa.PrivateKey) (string, error) {
+	t := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	t.Header["kid"] = kid
+	return t.SignedString(key)
+}
+
+// ================================ Helper Functions
+
+func apply(c, changes jwt.MapClaims) jwt.MapClaims {
+	for k, v := range changes {
+		if v == nil {
+			delete(c, k)
+			continue
+		}
+		c[k] = v
+	}
+	return c
+}
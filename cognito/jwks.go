@@ -0,0 +1,148 @@
+package cognito
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// JWKS timing constants
+const (
+	// DefaultJWKSTTL is how long fetched keys are used before fetching again
+	DefaultJWKSTTL = time.Hour
+	// jwksMinRefresh limits the refetches for unknown key ids, so tokens with made up key ids
+	// cannot turn every request into a fetch
+	jwksMinRefresh = 30 * time.Second
+)
+
+// ErrUnknownKey is returned for a key id missing from the key set
+var ErrUnknownKey = errors.New("unknown key id")
+
+// KeySet interface
+// Returns the public key a token was signed with
+type KeySet interface {
+	Key(kid string) (*rsa.PublicKey, error)
+}
+
+// LocalKeys type
+// A fixed key set, used by the tests and local tools to validate self signed tokens
+type LocalKeys map[string]*rsa.PublicKey
+
+// Key method
+func (k LocalKeys) Key(kid string) (*rsa.PublicKey, error) {
+	key, ok := k[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, kid)
+	}
+	return key, nil
+}
+
+// JWKS struct
+// Fetches the pool keys and keeps them for the TTL, fetching again early for an unknown key id
+// as Cognito may have rotated its keys
+type JWKS struct {
+	client  *http.Client
+	fetched time.Time
+	keys    LocalKeys
+	mu      sync.Mutex
+	now     func() time.Time
+	ttl     time.Duration
+	url     string
+}
+
+// NewJWKS function
+func NewJWKS(url string, ttl time.Duration) *JWKS {
+	return &JWKS{
+		client: &http.Client{Timeout: 5 * time.Second},
+		now:    time.Now,
+		ttl:    ttl,
+		url:    url,
+	}
+}
+
+// Key method
+func (j *JWKS) Key(kid string) (*rsa.PublicKey, error) {
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	age := j.now().Sub(j.fetched)
+	_, known := j.keys[kid]
+	if j.keys == nil || age >= j.ttl || (!known && age >= jwksMinRefresh) {
+		if err := j.fetch(); err != nil {
+			return nil, err
+		}
+	}
+
+	return j.keys.Key(kid)
+}
+
+// ================================ Helper Methods
+
+type jwk struct {
+	E   string `json:"e"`
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+}
+
+func (j *JWKS) fetch() error {
+
+	res, err := j.client.Get(j.url)
+	if err != nil {
+		return fmt.Errorf("Error fetching jwks: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("Error fetching jwks: %s", res.Status)
+	}
+
+	set := struct {
+		Keys []jwk `json:"keys"`
+	}{}
+	if err := json.NewDecoder(res.Body).Decode(&set); err != nil {
+		return fmt.Errorf("Error decoding jwks: %w", err)
+	}
+
+	keys := LocalKeys{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		key, err := rsaKey(k)
+		if err != nil {
+			return fmt.Errorf("Error decoding jwks key %s: %w", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+
+	j.keys = keys
+	j.fetched = j.now()
+
+	return nil
+}
+
+// ================================ Helper Functions
+
+func rsaKey(k jwk) (*rsa.PublicKey, error) {
+
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rsa.PublicKey{
+		E: int(new(big.Int).SetBytes(e).Int64()),
+		N: new(big.Int).SetBytes(n),
+	}, nil
+}
@@ -0,0 +1,85 @@
+package cognito
+
+import (
+	"errors"
+	"fmt"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+// Token struct
+// The result of a validated token
+type Token struct {
+	Claims    map[string]interface{}
+	Identity  *Identity
+	Principal string
+}
+
+// Validator interface
+type Validator interface {
+	Validate(token string) (*Token, error)
+}
+
+// JWTValidator struct
+// Checks the token signature against the key set, then its expiry and the pool Settings claims
+type JWTValidator struct {
+	keys     KeySet
+	settings *Settings
+}
+
+// NewValidator function
+func NewValidator(s *Settings, keys KeySet) *JWTValidator {
+	return &JWTValidator{keys: keys, settings: s}
+}
+
+// Validate method
+func (v *JWTValidator) Validate(token string) (*Token, error) {
+
+	claims := jwt.MapClaims{}
+	// only RS256 is accepted, so a token cannot choose a weaker algorithm or none
+	parser := &jwt.Parser{ValidMethods: []string{jwt.SigningMethodRS256.Alg()}}
+	_, err := parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, ok := t.Header["kid"].(string)
+		if !ok {
+			return nil, errors.New("expecting JWT header to have string kid")
+		}
+		return v.keys.Key(kid)
+	})
+	// the jwt library wraps the key lookup errors, which callers can still match with errors.Is
+	if err != nil {
+		return nil, err
+	}
+	// exp is optional to the jwt library, but Cognito always sets it
+	if _, ok := claims["exp"]; !ok {
+		return nil, errors.New("token has no expiry")
+	}
+
+	if err := v.settings.CheckClaims(claims); err != nil {
+		return nil, err
+	}
+
+	if name, ok := v.settings.Service(claims); ok {
+		client := claimString(claims, "client_id")
+		return &Token{
+			Claims:    claims,
+			Identity:  &Identity{Caller: CallerService, Name: name, UserID: client},
+			Principal: fmt.Sprintf("%s|%s", name, client),
+		}, nil
+	}
+
+	return &Token{
+		Claims:    claims,
+		Identity:  IdentityFromClaims(claims),
+		Principal: principal(claims),
+	}, nil
+}
+
+// ================================ Helper Functions
+
+// principal returns the user and app client as "username|client"
+func principal(claims map[string]interface{}) string {
+	if claimString(claims, "token_use") == TokenUseID {
+		return fmt.Sprintf("%s|%s", claimString(claims, "cognito:username"), claimString(claims, "aud"))
+	}
+	return fmt.Sprintf("%s|%s", claimString(claims, "username"), claimString(claims, "client_id"))
+}
@@ -0,0 +1,154 @@
+package cognito
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+	"github.com/pulpfree/univsales-wrksht-pdf/cognito/cognitotest"
+	"github.com/stretchr/testify/suite"
+)
+
+// ValidatorSuite struct
+type ValidatorSuite struct {
+	suite.Suite
+	key   *rsa.PrivateKey
+	other *rsa.PrivateKey
+	s     *Settings
+	v     *JWTValidator
+}
+
+// SetupSuite method
+func (suite *ValidatorSuite) SetupSuite() {
+	var err error
+	suite.key, err = cognitotest.NewKey()
+	suite.NoError(err)
+	suite.other, err = cognitotest.NewKey()
+	suite.NoError(err)
+}
+
+// SetupTest method
+func (suite *ValidatorSuite) SetupTest() {
+	suite.s = &Settings{ClientIDs: []string{cognitotest.ClientID}, PoolID: cognitotest.PoolID, Region: cognitotest.Region, TokenUse: []string{TokenUseAccess}}
+	suite.v = NewValidator(suite.s, LocalKeys{"k1": &suite.key.PublicKey})
+}
+
+// TestValidate method
+func (suite *ValidatorSuite) TestValidate() {
+
+	t, err := suite.v.Validate(suite.sign(cognitotest.Claims(nil), "k1", suite.key))
+	suite.NoError(err)
+	suite.Equal("jdoe|"+cognitotest.ClientID, t.Principal)
+	suite.Equal(&Identity{Caller: CallerUser, Groups: []string{"sales"}, Name: "jdoe", UserID: "8f1c-42"}, t.Identity)
+
+	// a client credentials token from a service client
+	suite.s.Services = map[string]string{"svcclient": "scheduler"}
+	t, err = suite.v.Validate(suite.sign(cognitotest.Claims(jwt.MapClaims{"client_id": "svcclient", "cognito:groups": nil, "sub": "svcclient", "username": nil}), "k1", suite.key))
+	suite.NoError(err)
+	suite.Equal("scheduler|svcclient", t.Principal)
+	suite.Equal(&Identity{Caller: CallerService, Name: "scheduler", UserID: "svcclient"}, t.Identity)
+}
+
+// TestRejected method
+func (suite *ValidatorSuite) TestRejected() {
+
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{"expired", suite.sign(cognitotest.Claims(jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}), "k1", suite.key), nil},
+		{"no expiry", suite.sign(cognitotest.Claims(jwt.MapClaims{"exp": nil}), "k1", suite.key), nil},
+		{"wrong issuer", suite.sign(cognitotest.Claims(jwt.MapClaims{"iss": "https://cognito-idp.ca-central-1.amazonaws.com/ca-central-1_other"}), "k1", suite.key), ErrIssuer},
+		{"wrong audience", suite.sign(cognitotest.Claims(jwt.MapClaims{"client_id": "otherclient"}), "k1", suite.key), ErrAudience},
+		{"wrong use", suite.sign(cognitotest.Claims(jwt.MapClaims{"token_use": TokenUseID}), "k1", suite.key), ErrTokenUse},
+		{"bad signature", suite.sign(cognitotest.Claims(nil), "k1", suite.other), nil},
+		{"unknown key", suite.sign(cognitotest.Claims(nil), "k2", suite.key), ErrUnknownKey},
+		{"hmac", suite.hmac(cognitotest.Claims(nil)), nil},
+		{"malformed", "not.a.token", nil},
+	}
+
+	for _, tt := range tests {
+		_, err := suite.v.Validate(tt.token)
+		suite.Error(err, tt.name)
+		if tt.err != nil {
+			suite.True(errors.Is(err, tt.err), "%s: %v", tt.name, err)
+		}
+	}
+}
+
+// TestJWKS method
+func (suite *ValidatorSuite) TestJWKS() {
+
+	fetches := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"alg": "RS256",
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(suite.key.E)).Bytes()),
+			"kid": "k1",
+			"kty": "RSA",
+			"n":   base64.RawURLEncoding.EncodeToString(suite.key.N.Bytes()),
+			"use": "sig",
+		}}})
+	}))
+	defer srv.Close()
+
+	now := time.Now()
+	j := NewJWKS(srv.URL, time.Hour)
+	j.now = func() time.Time { return now }
+
+	key, err := j.Key("k1")
+	suite.NoError(err)
+	suite.Equal(suite.key.PublicKey, *key)
+	_, err = j.Key("k1")
+	suite.NoError(err)
+	suite.Equal(1, fetches)
+
+	// an unknown key id refetches, but not again straight away
+	now = now.Add(time.Minute)
+	_, err = j.Key("k2")
+	suite.True(errors.Is(err, ErrUnknownKey))
+	suite.Equal(2, fetches)
+	_, err = j.Key("k2")
+	suite.True(errors.Is(err, ErrUnknownKey))
+	suite.Equal(2, fetches)
+
+	now = now.Add(time.Hour)
+	_, err = j.Key("k1")
+	suite.NoError(err)
+	suite.Equal(3, fetches)
+
+	// tokens validate against the fetched keys
+	_, err = NewValidator(suite.s, j).Validate(suite.sign(cognitotest.Claims(nil), "k1", suite.key))
+	suite.NoError(err)
+}
+
+// ================================ Helper Methods
+
+func (suite *ValidatorSuite) sign(claims jwt.MapClaims, kid string, key *rsa.PrivateKey) string {
+	s, err := cognitotest.Sign(claims, kid, key)
+	suite.NoError(err)
+	return s
+}
+
+// hmac signs with the public modulus as an hmac secret, the algorithm confusion attack
+func (suite *ValidatorSuite) hmac(claims jwt.MapClaims) string {
+	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	t.Header["kid"] = "k1"
+	s, err := t.SignedString(suite.key.N.Bytes())
+	suite.NoError(err)
+	return s
+}
+
+// TestValidatorSuite function
+func TestValidatorSuite(t *testing.T) {
+	suite.Run(t, new(ValidatorSuite))
+}
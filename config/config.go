@@ -3,22 +3,19 @@ package config
 import (
 	"errors"
 	"fmt"
-	"io/ioutil"
-	"os"
-	"path"
-	"reflect"
+	"strconv"
 	"strings"
-
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/ssm"
-	yaml "gopkg.in/yaml.v2"
+	"time"
 )
 
 // Config struct
+// Providers replaces the providers run for the stage when set, as for tests and tools that run offline
 type Config struct {
 	config
 	DefaultsFilePath string
+	EnvFilePath      string
+	Providers        []Provider
+	sources          map[string]string
 }
 
 // StageEnvironment string
@@ -33,14 +30,19 @@ const (
 )
 
 /*
-Steps to setting config:
-1. Unmarshal yaml defaults file into config struct
-2. Fetch any environment vars and overwrite struct values
-3. Fetch KMS parameters and overwrite struct values
+Steps to setting config, each provider overwriting the values before it:
+1. Unmarshal yaml defaults file
+2. Fetch any environment vars
+3. Read the local env file for the values the environment vars leave unset, when the stage reads local files
+4. Fetch SSM parameters, when the stage uses AWS
+The stage the values resolve to after each step selects the steps that follow, see stageProviders
 */
 
 const defaultFileName = "defaults.yml"
 
+// maxURLExpiry is the longest expiry S3 allows for a presigned url
+const maxURLExpiry = 7 * 24 * time.Hour
+
 var (
 	defs = &defaults{}
 )
@@ -48,18 +50,26 @@ var (
 // Load method
 func (c *Config) Load() (err error) {
 
-	err = c.setDefaults()
-	if err != nil {
-		return err
-	}
-	err = c.setEnvVars()
-	if err != nil {
-		return err
+	defs = &defaults{}
+	c.sources = map[string]string{}
+
+	providers := c.Providers
+	if providers == nil {
+		providers = Providers()
 	}
-	err = c.setSSMParams()
-	if err != nil {
-		return err
+	for _, p := range providers {
+		if !c.enabled(p) {
+			continue
+		}
+		vals, err := p.Values(c)
+		if err != nil {
+			return err
+		}
+		if err = c.apply(p.Name(), vals); err != nil {
+			return err
+		}
 	}
+
 	err = c.setFinal()
 	if err != nil {
 		return err
@@ -81,31 +91,25 @@ func (c *Config) SetStageEnv(env string) error {
 	return err
 }
 
-// GetMongoConnectURL method
-func (c *Config) GetMongoConnectURL() string {
-	return c.DBConnectURL
+// Source method
+// Returns the provider the value of the defaults field key came from, such as ssm for DBName
+func (c *Config) Source(key string) string {
+	return c.sources[key]
 }
 
-// this must be called first in c.Load
-func (c *Config) setDefaults() (err error) {
-
-	if c.DefaultsFilePath == "" {
-		dir, _ := os.Getwd()
-		c.DefaultsFilePath = path.Join(dir, defaultFileName)
-	}
-
-	file, err := ioutil.ReadFile(c.DefaultsFilePath)
-	if err != nil {
-		return err
+// Sources method
+// Returns the provider of each value, keyed by the defaults field names
+func (c *Config) Sources() map[string]string {
+	m := make(map[string]string, len(c.sources))
+	for k, v := range c.sources {
+		m[k] = v
 	}
+	return m
+}
 
-	err = yaml.Unmarshal([]byte(file), &defs)
-	if err != nil {
-		return err
-	}
-	err = c.validateStage()
-
-	return err
+// GetMongoConnectURL method
+func (c *Config) GetMongoConnectURL() string {
+	return c.DBConnectURL
 }
 
 // validateStage method to validate Stage value
@@ -138,65 +142,6 @@ func (c *Config) validateStage() error {
 	return nil
 }
 
-// sets any environment variables that match the default struct fields
-func (c *Config) setEnvVars() (err error) {
-
-	vals := reflect.Indirect(reflect.ValueOf(defs))
-	for i := 0; i < vals.NumField(); i++ {
-		nm := vals.Type().Field(i).Name
-		if e := os.Getenv(nm); e != "" {
-			vals.Field(i).SetString(e)
-		}
-		// If field is Stage, validate and return error if required
-		if nm == "Stage" {
-			err = c.validateStage()
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	return err
-}
-
-func (c *Config) setSSMParams() (err error) {
-
-	s := []string{"", string(c.GetStageEnv()), defs.SsmPath}
-	paramPath := aws.String(strings.Join(s, "/"))
-
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String(defs.AWSRegion),
-	})
-	if err != nil {
-		return err
-	}
-
-	svc := ssm.New(sess)
-	res, err := svc.GetParametersByPath(&ssm.GetParametersByPathInput{
-		Path:           paramPath,
-		WithDecryption: aws.Bool(true),
-	})
-	if err != nil {
-		return err
-	}
-
-	paramLen := len(res.Parameters)
-	if paramLen == 0 {
-		err = fmt.Errorf("Error fetching ssm params, total number found: %d", paramLen)
-	}
-	// Get struct keys so we can test before attempting to set
-	t := reflect.ValueOf(defs).Elem()
-	for _, r := range res.Parameters {
-		paramName := strings.Split(*r.Name, "/")[3]
-		structKey := t.FieldByName(paramName)
-		if structKey.IsValid() {
-			structKey.Set(reflect.ValueOf(*r.Value))
-		}
-	}
-
-	return err
-}
-
 // Build a url used in mgo.Dial as described in: https://godoc.org/gopkg.in/mgo.v2#Dial
 func (c *Config) setDBConnectURL() *Config {
 
@@ -230,12 +175,111 @@ func (c *Config) setAWSConnectURL() {
 func (c *Config) setFinal() (err error) {
 
 	c.AWSRegion = defs.AWSRegion
-	c.CognitoClientID = defs.CognitoClientID
+	c.CognitoPoolID = defs.CognitoPoolID
+	c.CognitoRegion = defs.CognitoRegion
 	c.DBName = defs.DBName
 	c.S3Bucket = defs.S3Bucket
 	c.DocAuthor = defs.DocAuthor
+	c.EmailFrom = defs.EmailFrom
+	c.EventBusName = defs.EventBusName
+	c.EventTopicARN = defs.EventTopicARN
+	c.EventsFile = defs.EventsFile
+	c.JobQueueURL = defs.JobQueueURL
+	c.LogLevel = defs.LogLevel
 	c.LogoURI = defs.LogoURI
+	c.Metrics = defs.Metrics
+	c.MetricsNamespace = defs.MetricsNamespace
+	c.OutputDir = defs.OutputDir
+	c.OutputURL = defs.OutputURL
+	c.SMTPAddr = defs.SMTPAddr
+	c.SMTPPassword = defs.SMTPPassword
+	c.SMTPUser = defs.SMTPUser
+	c.TraceEndpoint = defs.TraceEndpoint
+	c.TraceExporter = defs.TraceExporter
+	c.TraceToken = defs.TraceToken
+
+	// comma separated lists, so they can be set from an environment variable or ssm parameter
+	c.CORSOrigins = splitList(defs.CORSOrigins)
+	c.CognitoClientIDs = splitList(defs.CognitoClientID)
+	c.CognitoTokenUse = splitList(defs.CognitoTokenUse)
+	c.InstallerEmails = splitList(defs.InstallerEmails)
+	c.LogRedact = splitList(defs.LogRedact)
+
+	c.AuthPolicies, err = splitPolicies("AuthPolicies", defs.AuthPolicies)
+	if err != nil {
+		return err
+	}
+	c.ServicePolicies, err = splitPolicies("ServicePolicies", defs.ServicePolicies)
+	if err != nil {
+		return err
+	}
+	c.ServiceClients, err = splitPairs(defs.ServiceClients)
+	if err != nil {
+		return fmt.Errorf("Invalid ServiceClients value: %s", err)
+	}
+
+	c.BatchConcurrency, err = strconv.Atoi(defs.BatchConcurrency)
+	if err != nil || c.BatchConcurrency < 1 {
+		return fmt.Errorf("Invalid BatchConcurrency value: %s", defs.BatchConcurrency)
+	}
+
+	c.RetainVersions, err = strconv.Atoi(defs.RetainVersions)
+	if err != nil || c.RetainVersions < 0 {
+		return fmt.Errorf("Invalid RetainVersions value: %s", defs.RetainVersions)
+	}
+
+	c.S3URLExpiry, err = time.ParseDuration(defs.S3URLExpiry)
+	if err != nil {
+		return fmt.Errorf("Invalid S3URLExpiry value: %s", defs.S3URLExpiry)
+	}
+
+	c.EmailURLExpiry, err = time.ParseDuration(defs.EmailURLExpiry)
+	if err != nil || c.EmailURLExpiry <= 0 || c.EmailURLExpiry > maxURLExpiry {
+		return fmt.Errorf("Invalid EmailURLExpiry value: %s", defs.EmailURLExpiry)
+	}
+
 	err = c.validateStage()
 
 	return err
 }
+
+func splitList(s string) []string {
+	var l []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			l = append(l, v)
+		}
+	}
+	return l
+}
+
+// splitPairs parses a comma separated list of name=value pairs, such as "scheduler=4k1q2b3c"
+func splitPairs(s string) (map[string]string, error) {
+	m := map[string]string{}
+	for _, v := range splitList(s) {
+		kv := strings.SplitN(v, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" || strings.TrimSpace(kv[1]) == "" {
+			return nil, fmt.Errorf("expected name=value, got %s", v)
+		}
+		m[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return m, nil
+}
+
+// splitPolicies parses the group policy table, such as "sales=POST /,GET /jobs/*;installers=GET /worksheets/*",
+// into the rules of each group. The error names the setting field the table was read from
+func splitPolicies(field, s string) (map[string][]string, error) {
+	m := map[string][]string{}
+	for _, v := range strings.Split(s, ";") {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		kv := strings.SplitN(v, "=", 2)
+		group := strings.TrimSpace(kv[0])
+		if len(kv) != 2 || group == "" {
+			return nil, fmt.Errorf("Invalid %s entry: %s", field, v)
+		}
+		m[group] = append(m[group], splitList(kv[1])...)
+	}
+	return m, nil
+}
@@ -18,23 +18,21 @@ type IntegSuite struct {
 // SetupTest method
 func (suite *IntegSuite) SetupTest() {
 
-	suite.c = &Config{}
+	suite.c = &Config{Providers: []Provider{&defaultsProvider{}, &envProvider{}}}
 
 	os.Setenv("Stage", "test")
-	suite.c.setDefaults()
-	suite.c.setEnvVars()
+	suite.c.Load()
 }
 
-// TestSetSSMParams function
-// this test assumes that the S3Bucket is set
-func (suite *IntegSuite) TestSetSSMParams() {
+// TestSSMProvider function
+// this test assumes that the DBName parameter is set
+func (suite *IntegSuite) TestSSMProvider() {
 
 	DBNameBefore := defs.DBName
-	err := suite.c.setSSMParams()
+	vals, err := (&ssmProvider{}).Values(suite.c)
 	suite.NoError(err)
 
-	DBNameAfter := defs.DBName
-	suite.True(strings.Compare(DBNameBefore, DBNameAfter) != 0)
+	suite.True(strings.Compare(DBNameBefore, vals["DBName"]) != 0)
 }
 
 // TestLoadProduction
@@ -42,10 +40,12 @@ func (suite *IntegSuite) TestLoadProduction() {
 
 	os.Setenv("Stage", "prod")
 
+	suite.c.Providers = nil
 	err := suite.c.Load()
 	fmt.Printf("suite.c %+v\n", suite.c)
 	suite.NoError(err)
 	suite.NotEmpty(suite.c.AWSRegion)
+	suite.Equal(SourceSSM, suite.c.Source("DBName"))
 }
 
 func (suite *IntegSuite) TestSetStageEnv() {
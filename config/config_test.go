@@ -0,0 +1,223 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/stretchr/testify/suite"
+)
+
+// UnitSuite struct
+type UnitSuite struct {
+	suite.Suite
+	dir string
+}
+
+// SetupTest method
+func (suite *UnitSuite) SetupTest() {
+	var err error
+	suite.dir, err = ioutil.TempDir("", "config")
+	suite.NoError(err)
+	os.Setenv("Stage", string(TestEnv))
+}
+
+// TearDownTest method
+func (suite *UnitSuite) TearDownTest() {
+	os.RemoveAll(suite.dir)
+	os.Unsetenv("Stage")
+	os.Unsetenv("LogLevel")
+}
+
+// TestLoadOffline method
+// The test stage reads the defaults, environment and env file, without calling SSM.
+// The environment takes precedence over the env file
+func (suite *UnitSuite) TestLoadOffline() {
+
+	os.Setenv("LogLevel", "warn")
+	c := &Config{
+		DefaultsFilePath: defaultFileName,
+		EnvFilePath:      suite.envFile("# local secrets\nDBName=wrksht\nexport SMTPPassword=\"p@ss=word\"\nLogLevel='debug'\nUnknownKey=1\n"),
+	}
+	suite.NoError(c.Load())
+
+	suite.Equal(TestEnv, c.GetStageEnv())
+	suite.Equal("wrksht", c.DBName)
+	suite.Equal("p@ss=word", c.SMTPPassword)
+	suite.Equal("warn", c.LogLevel)
+	suite.Equal("ca-central-1", c.AWSRegion)
+	suite.Equal(168*time.Hour, c.EmailURLExpiry)
+
+	suite.Equal(SourceDefaults, c.Source("AWSRegion"))
+	suite.Equal(SourceEnv, c.Source("Stage"))
+	suite.Equal(SourceFile, c.Source("DBName"))
+	suite.Equal(SourceEnv, c.Source("LogLevel"))
+	suite.Equal("", c.Source("UnknownKey"))
+	suite.Equal(SourceFile, c.Sources()["SMTPPassword"])
+
+	// the env file is optional
+	c = &Config{DefaultsFilePath: defaultFileName, EnvFilePath: path.Join(suite.dir, "missing.env")}
+	suite.NoError(c.Load())
+	suite.Equal(SourceDefaults, c.Source("DBName"))
+}
+
+// TestStageProviders method
+func (suite *UnitSuite) TestStageProviders() {
+
+	tests := []struct {
+		stage   StageEnvironment
+		enabled []string
+	}{
+		{DevEnv, []string{SourceDefaults, SourceEnv, SourceFile, SourceSSM}},
+		{StageEnv, []string{SourceDefaults, SourceEnv, SourceSSM}},
+		{TestEnv, []string{SourceDefaults, SourceEnv, SourceFile}},
+		{ProdEnv, []string{SourceDefaults, SourceEnv, SourceSSM}},
+	}
+
+	for _, tt := range tests {
+		c := &Config{}
+		c.Stage = tt.stage
+		var enabled []string
+		for _, p := range Providers() {
+			if c.enabled(p) {
+				enabled = append(enabled, p.Name())
+			}
+		}
+		suite.Equal(tt.enabled, enabled, tt.stage)
+	}
+}
+
+// TestProviders method
+// Explicit providers run in the given order whatever the stage
+func (suite *UnitSuite) TestProviders() {
+
+	os.Setenv("Stage", string(ProdEnv))
+	c := &Config{
+		DefaultsFilePath: defaultFileName,
+		Providers: []Provider{
+			&defaultsProvider{},
+			&envProvider{},
+			staticProvider{"DBName": "first", "S3Bucket": "bucket"},
+			staticProvider{"DBName": "second"},
+		},
+	}
+	suite.NoError(c.Load())
+
+	suite.Equal(ProdEnv, c.GetStageEnv())
+	suite.Equal("second", c.DBName)
+	suite.Equal("bucket", c.S3Bucket)
+	suite.Equal("static", c.Source("DBName"))
+
+	// an invalid stage from any provider fails the load
+	c.Providers = append(c.Providers, staticProvider{"Stage": "qa"})
+	suite.EqualError(c.Load(), "Invalid Stage type")
+}
+
+// TestInvalidValues method
+func (suite *UnitSuite) TestInvalidValues() {
+
+	tests := []struct {
+		key   string
+		value string
+		err   string
+	}{
+		{"EmailURLExpiry", "week", "Invalid EmailURLExpiry value: week"},
+		{"EmailURLExpiry", "0s", "Invalid EmailURLExpiry value: 0s"},
+		{"EmailURLExpiry", "169h", "Invalid EmailURLExpiry value: 169h"},
+		{"AuthPolicies", "sales", "Invalid AuthPolicies entry: sales"},
+		{"ServicePolicies", "scheduler=POST /;=GET /jobs/*", "Invalid ServicePolicies entry: =GET /jobs/*"},
+	}
+
+	for _, tt := range tests {
+		c := &Config{
+			DefaultsFilePath: defaultFileName,
+			Providers:        []Provider{&defaultsProvider{}, staticProvider{"Stage": string(TestEnv), tt.key: tt.value}},
+		}
+		suite.EqualError(c.Load(), tt.err, tt.key)
+	}
+}
+
+// TestSSMProvider method
+// Parameters are read from every page, a page holds at most 10
+func (suite *UnitSuite) TestSSMProvider() {
+
+	defs = &defaults{SsmPath: "univsales-wrksht-pdf"}
+	c := &Config{}
+	c.Stage = ProdEnv
+
+	client := &ssmPages{}
+	for i := 0; i < 23; i++ {
+		client.params = append(client.params, &ssm.Parameter{
+			Name:  aws.String(fmt.Sprintf("/prod/univsales-wrksht-pdf/Key%d", i)),
+			Value: aws.String(strconv.Itoa(i)),
+		})
+	}
+	vals, err := (&ssmProvider{client: client}).Values(c)
+	suite.NoError(err)
+	suite.Len(vals, 23)
+	suite.Equal("22", vals["Key22"])
+	suite.Equal(3, client.pages)
+	suite.Equal("/prod/univsales-wrksht-pdf", client.path)
+
+	_, err = (&ssmProvider{client: &ssmPages{}}).Values(c)
+	suite.EqualError(err, "Error fetching ssm params, total number found: 0")
+}
+
+// TestParseEnvFile method
+func (suite *UnitSuite) TestParseEnvFile() {
+
+	vals, err := parseEnvFile([]byte("\n  # comment\nA=1\nexport B = two words \nC=\"quoted # value\"\nD=\nE='x\n"))
+	suite.NoError(err)
+	suite.Equal(map[string]string{"A": "1", "B": "two words", "C": "quoted # value", "D": "", "E": "'x"}, vals)
+
+	for _, bad := range []string{"NOVALUE", "=value"} {
+		_, err = parseEnvFile([]byte("A=1\n" + bad))
+		suite.EqualError(err, "line 2: expected KEY=value", bad)
+	}
+}
+
+// ================================ Helper Methods
+
+func (suite *UnitSuite) envFile(body string) string {
+	p := path.Join(suite.dir, ".env")
+	suite.NoError(ioutil.WriteFile(p, []byte(body), 0600))
+	return p
+}
+
+type staticProvider map[string]string
+
+func (p staticProvider) Name() string { return "static" }
+
+func (p staticProvider) Values(c *Config) (map[string]string, error) { return p, nil }
+
+// ssmPages returns params in pages of 10, as SSM does
+type ssmPages struct {
+	pages  int
+	params []*ssm.Parameter
+	path   string
+}
+
+func (c *ssmPages) GetParametersByPathPages(in *ssm.GetParametersByPathInput, fn func(*ssm.GetParametersByPathOutput, bool) bool) error {
+	c.path = aws.StringValue(in.Path)
+	for start := 0; ; start += 10 {
+		end := start + 10
+		if end > len(c.params) {
+			end = len(c.params)
+		}
+		c.pages++
+		if !fn(&ssm.GetParametersByPathOutput{Parameters: c.params[start:end]}, end == len(c.params)) || end == len(c.params) {
+			return nil
+		}
+	}
+}
+
+// TestUnitSuite function
+func TestUnitSuite(t *testing.T) {
+	suite.Run(t, new(UnitSuite))
+}
@@ -0,0 +1,257 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Source constants, the names of the providers a value can come from
+const (
+	SourceDefaults = "defaults"
+	SourceEnv      = "env"
+	SourceFile     = "file"
+	SourceSSM      = "ssm"
+)
+
+const defaultEnvFileName = ".env"
+
+// stageProviders lists the providers run after the defaults for each stage.
+// The test stage runs without AWS, and the deployed stages do not read local files
+var stageProviders = map[StageEnvironment][]string{
+	DevEnv:   {SourceEnv, SourceFile, SourceSSM},
+	StageEnv: {SourceEnv, SourceSSM},
+	TestEnv:  {SourceEnv, SourceFile},
+	ProdEnv:  {SourceEnv, SourceSSM},
+}
+
+// Provider interface
+// Returns config values keyed by the defaults field names, such as DBName
+type Provider interface {
+	Name() string
+	Values(c *Config) (map[string]string, error)
+}
+
+// Providers function
+// Returns the providers in the order Load runs them: defaults file, environment variables,
+// local env file and SSM parameters. The env file only sets what the environment leaves unset
+func Providers() []Provider {
+	return []Provider{
+		&defaultsProvider{},
+		&envProvider{},
+		&fileProvider{},
+		&ssmProvider{},
+	}
+}
+
+// ================================ Helper Methods
+
+// enabled reports whether Load runs p. Explicitly set providers always run, the defaults always
+// run first, and the others run when listed for the stage the values so far resolve to
+func (c *Config) enabled(p Provider) bool {
+	if c.Providers != nil || p.Name() == SourceDefaults {
+		return true
+	}
+	for _, n := range stageProviders[c.Stage] {
+		if n == p.Name() {
+			return true
+		}
+	}
+	return false
+}
+
+// apply sets the values of the defaults fields, recording the provider as their source
+func (c *Config) apply(source string, vals map[string]string) error {
+
+	t := reflect.ValueOf(defs).Elem()
+	for k, v := range vals {
+		f := t.FieldByName(k)
+		if !f.IsValid() || f.Kind() != reflect.String {
+			continue
+		}
+		f.SetString(v)
+		c.sources[k] = source
+	}
+
+	return c.validateStage()
+}
+
+// defaultsProvider reads the yaml defaults file, this is the only provider that is required
+type defaultsProvider struct{}
+
+func (p *defaultsProvider) Name() string { return SourceDefaults }
+
+func (p *defaultsProvider) Values(c *Config) (map[string]string, error) {
+
+	if c.DefaultsFilePath == "" {
+		dir, _ := os.Getwd()
+		c.DefaultsFilePath = path.Join(dir, defaultFileName)
+	}
+
+	file, err := ioutil.ReadFile(c.DefaultsFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &defaults{}
+	if err := yaml.Unmarshal(file, d); err != nil {
+		return nil, err
+	}
+
+	return fields(d), nil
+}
+
+// envProvider reads the environment variables named as the defaults fields, ignoring empty values
+type envProvider struct{}
+
+func (p *envProvider) Name() string { return SourceEnv }
+
+func (p *envProvider) Values(c *Config) (map[string]string, error) {
+
+	vals := map[string]string{}
+	for k := range fields(defs) {
+		if e := os.Getenv(k); e != "" {
+			vals[k] = e
+		}
+	}
+
+	return vals, nil
+}
+
+// fileProvider reads a local env file of KEY=value lines, such as the secrets of a developer machine.
+// The file is optional, and its keys set in the environment are skipped so the environment takes precedence.
+// It runs after the environment all the same, as the environment selects the stage that reads the file
+type fileProvider struct{}
+
+func (p *fileProvider) Name() string { return SourceFile }
+
+func (p *fileProvider) Values(c *Config) (map[string]string, error) {
+
+	if c.EnvFilePath == "" {
+		dir, _ := os.Getwd()
+		c.EnvFilePath = path.Join(dir, defaultEnvFileName)
+	}
+
+	file, err := ioutil.ReadFile(c.EnvFilePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	vals, err := parseEnvFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading %s: %s", c.EnvFilePath, err)
+	}
+	for k := range vals {
+		if os.Getenv(k) != "" {
+			delete(vals, k)
+		}
+	}
+
+	return vals, nil
+}
+
+// ssmProvider reads the parameters under /stage/SsmPath, which needs AWS credentials and network.
+// client is created from the config region when not set
+type ssmProvider struct {
+	client ssmClient
+}
+
+// ssmClient is the part of the SSM api the provider uses
+type ssmClient interface {
+	GetParametersByPathPages(*ssm.GetParametersByPathInput, func(*ssm.GetParametersByPathOutput, bool) bool) error
+}
+
+func (p *ssmProvider) Name() string { return SourceSSM }
+
+func (p *ssmProvider) Values(c *Config) (map[string]string, error) {
+
+	s := []string{"", string(c.GetStageEnv()), defs.SsmPath}
+	paramPath := aws.String(strings.Join(s, "/"))
+
+	svc := p.client
+	if svc == nil {
+		sess, err := session.NewSession(&aws.Config{
+			Region: aws.String(defs.AWSRegion),
+		})
+		if err != nil {
+			return nil, err
+		}
+		svc = ssm.New(sess)
+	}
+
+	// a page holds at most 10 parameters
+	vals := map[string]string{}
+	err := svc.GetParametersByPathPages(&ssm.GetParametersByPathInput{
+		Path:           paramPath,
+		WithDecryption: aws.Bool(true),
+	}, func(page *ssm.GetParametersByPathOutput, lastPage bool) bool {
+		for _, r := range page.Parameters {
+			vals[path.Base(*r.Name)] = *r.Value
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	paramLen := len(vals)
+	if paramLen == 0 {
+		return nil, fmt.Errorf("Error fetching ssm params, total number found: %d", paramLen)
+	}
+
+	return vals, nil
+}
+
+// ================================ Helper Functions
+
+// fields returns the string fields of d keyed by field name
+func fields(d *defaults) map[string]string {
+	vals := map[string]string{}
+	v := reflect.ValueOf(d).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if v.Field(i).Kind() == reflect.String {
+			vals[v.Type().Field(i).Name] = v.Field(i).String()
+		}
+	}
+	return vals
+}
+
+// parseEnvFile parses KEY=value lines, skipping blank lines and # comments.
+// Lines may start with export, and values may be wrapped in single or double quotes
+func parseEnvFile(b []byte) (map[string]string, error) {
+
+	vals := map[string]string{}
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+		kv := strings.SplitN(line, "=", 2)
+		key := strings.TrimSpace(kv[0])
+		if len(kv) != 2 || key == "" {
+			return nil, fmt.Errorf("line %d: expected KEY=value", n)
+		}
+		val := strings.TrimSpace(kv[1])
+		if len(val) > 1 && (val[0] == '"' || val[0] == '\'') && val[len(val)-1] == val[0] {
+			val = val[1 : len(val)-1]
+		}
+		vals[key] = val
+	}
+
+	return vals, sc.Err()
+}
@@ -1,27 +1,87 @@
 package config
 
+import "time"
+
 // defaults struct
 type defaults struct {
-	AWSRegion  string `yaml:"AWSRegion"`
-	CognitoClientID string `yaml:"CognitoClientID"`
-	DBHost     string `yaml:"DBHost"`
-	DBName     string `yaml:"DBName"`
-	DBPassword string `yaml:"DBPassword"`
-	DBUser     string `yaml:"DBUser"`
-	DocAuthor  string `yaml:"DocAuthor"`
-	LogoURI    string `yaml:"LogoURI"`
-	S3Bucket   string `yaml:"S3Bucket"`
-	SsmPath    string `yaml:"SsmPath"`
-	Stage      string `yaml:"Stage"`
+	AWSRegion        string `yaml:"AWSRegion"`
+	AuthPolicies     string `yaml:"AuthPolicies"`
+	BatchConcurrency string `yaml:"BatchConcurrency"`
+	CORSOrigins      string `yaml:"CORSOrigins"`
+	CognitoClientID  string `yaml:"CognitoClientID"`
+	CognitoPoolID    string `yaml:"CognitoPoolID"`
+	CognitoRegion    string `yaml:"CognitoRegion"`
+	CognitoTokenUse  string `yaml:"CognitoTokenUse"`
+	DBHost           string `yaml:"DBHost"`
+	DBName           string `yaml:"DBName"`
+	DBPassword       string `yaml:"DBPassword"`
+	DBUser           string `yaml:"DBUser"`
+	DocAuthor        string `yaml:"DocAuthor"`
+	EmailFrom        string `yaml:"EmailFrom"`
+	EmailURLExpiry   string `yaml:"EmailURLExpiry"`
+	EventBusName     string `yaml:"EventBusName"`
+	EventTopicARN    string `yaml:"EventTopicARN"`
+	EventsFile       string `yaml:"EventsFile"`
+	InstallerEmails  string `yaml:"InstallerEmails"`
+	JobQueueURL      string `yaml:"JobQueueURL"`
+	LogLevel         string `yaml:"LogLevel"`
+	LogRedact        string `yaml:"LogRedact"`
+	LogoURI          string `yaml:"LogoURI"`
+	Metrics          string `yaml:"Metrics"`
+	MetricsNamespace string `yaml:"MetricsNamespace"`
+	OutputDir        string `yaml:"OutputDir"`
+	OutputURL        string `yaml:"OutputURL"`
+	RetainVersions   string `yaml:"RetainVersions"`
+	S3Bucket         string `yaml:"S3Bucket"`
+	S3URLExpiry      string `yaml:"S3URLExpiry"`
+	SMTPAddr         string `yaml:"SMTPAddr"`
+	SMTPPassword     string `yaml:"SMTPPassword"`
+	SMTPUser         string `yaml:"SMTPUser"`
+	ServiceClients   string `yaml:"ServiceClients"`
+	ServicePolicies  string `yaml:"ServicePolicies"`
+	SsmPath          string `yaml:"SsmPath"`
+	Stage            string `yaml:"Stage"`
+	TraceEndpoint    string `yaml:"TraceEndpoint"`
+	TraceExporter    string `yaml:"TraceExporter"`
+	TraceToken       string `yaml:"TraceToken"`
 }
 
 type config struct {
-	AWSRegion    string
-	CognitoClientID string
-	DBConnectURL string
-	DBName       string
-	DocAuthor    string
-	LogoURI      string
-	S3Bucket     string
-	Stage        StageEnvironment
+	AWSRegion        string
+	AuthPolicies     map[string][]string
+	BatchConcurrency int
+	CORSOrigins      []string
+	CognitoClientIDs []string
+	CognitoPoolID    string
+	CognitoRegion    string
+	CognitoTokenUse  []string
+	DBConnectURL     string
+	DBName           string
+	DocAuthor        string
+	EmailFrom        string
+	EmailURLExpiry   time.Duration
+	EventBusName     string
+	EventTopicARN    string
+	EventsFile       string
+	InstallerEmails  []string
+	JobQueueURL      string
+	LogLevel         string
+	LogRedact        []string
+	LogoURI          string
+	Metrics          string
+	MetricsNamespace string
+	OutputDir        string
+	OutputURL        string
+	RetainVersions   int
+	S3Bucket         string
+	S3URLExpiry      time.Duration
+	SMTPAddr         string
+	SMTPPassword     string
+	SMTPUser         string
+	ServiceClients   map[string]string
+	ServicePolicies  map[string][]string
+	Stage            StageEnvironment
+	TraceEndpoint    string
+	TraceExporter    string
+	TraceToken       string
 }
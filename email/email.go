@@ -0,0 +1,220 @@
+package email
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/pulpfree/univsales-wrksht-pdf/awsservices"
+	"github.com/pulpfree/univsales-wrksht-pdf/config"
+)
+
+// Recipient constants, expanded by Recipients to the addresses they stand for
+const (
+	RecipientCustomer   = "customer"
+	RecipientInstallers = "installers"
+)
+
+// Options struct
+// Email delivery options of a worksheet request
+type Options struct {
+	Attach     bool     `json:"attach,omitempty"`
+	Recipients []string `json:"recipients,omitempty"`
+	Template   string   `json:"template,omitempty"`
+}
+
+// Message struct
+type Message struct {
+	Attachment *Attachment
+	Body       string
+	From       string
+	Subject    string
+	To         []string
+}
+
+// Attachment struct
+type Attachment struct {
+	Body        []byte
+	ContentType string
+	Name        string
+}
+
+// Sender interface
+type Sender interface {
+	Send(m *Message) error
+}
+
+// New function
+// Returns an SMTP sender when an SMTP server is configured, otherwise SES
+func New(cfg *config.Config) Sender {
+	if cfg.SMTPAddr != "" {
+		return NewSMTP(cfg)
+	}
+	return NewSES(cfg)
+}
+
+// Recipients function
+// Expands the customer and installers recipients, without any recipients both are used.
+// A customer without an email address is skipped
+func Recipients(recipients []string, customer string, installers []string) []string {
+
+	if len(recipients) == 0 {
+		recipients = []string{RecipientCustomer, RecipientInstallers}
+	}
+
+	seen := map[string]bool{}
+	to := []string{}
+	add := func(addr string) {
+		if addr != "" && !seen[strings.ToLower(addr)] {
+			seen[strings.ToLower(addr)] = true
+			to = append(to, addr)
+		}
+	}
+
+	for _, r := range recipients {
+		switch r {
+		case RecipientCustomer:
+			add(customer)
+		case RecipientInstallers:
+			for _, addr := range installers {
+				add(addr)
+			}
+		default:
+			add(r)
+		}
+	}
+
+	return to
+}
+
+// ValidateRecipient function
+func ValidateRecipient(r string) error {
+	if r == RecipientCustomer || r == RecipientInstallers {
+		return nil
+	}
+	if _, err := mail.ParseAddress(r); err != nil {
+		return fmt.Errorf("must be an email address, %s or %s", RecipientCustomer, RecipientInstallers)
+	}
+	return nil
+}
+
+// SES struct
+type SES struct {
+	cfg *config.Config
+}
+
+// NewSES function
+func NewSES(cfg *config.Config) *SES {
+	return &SES{cfg: cfg}
+}
+
+// Send method
+func (s *SES) Send(m *Message) error {
+
+	raw, err := m.Bytes()
+	if err != nil {
+		return err
+	}
+
+	return awsservices.SendRawEmail(m.From, m.To, raw, s.cfg)
+}
+
+// SMTP struct
+// Sends through a plain SMTP server, such as a local test server
+type SMTP struct {
+	cfg *config.Config
+}
+
+// NewSMTP function
+func NewSMTP(cfg *config.Config) *SMTP {
+	return &SMTP{cfg: cfg}
+}
+
+// Send method
+// Authenticates only when a user is configured
+func (s *SMTP) Send(m *Message) error {
+
+	raw, err := m.Bytes()
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if s.cfg.SMTPUser != "" {
+		host := strings.Split(s.cfg.SMTPAddr, ":")[0]
+		auth = smtp.PlainAuth("", s.cfg.SMTPUser, s.cfg.SMTPPassword, host)
+	}
+
+	return smtp.SendMail(s.cfg.SMTPAddr, auth, m.From, m.To, raw)
+}
+
+// Bytes method
+// Returns the message in MIME format, with the attachment as a second part
+func (m *Message) Bytes() ([]byte, error) {
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	hdr := []string{
+		"From: " + m.From,
+		"To: " + strings.Join(m.To, ", "),
+		"Subject: " + mime.QEncoding.Encode("utf-8", m.Subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/mixed; boundary=" + w.Boundary(),
+	}
+	buf.WriteString(strings.Join(hdr, "\r\n") + "\r\n\r\n")
+
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"8bit"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write([]byte(m.Body)); err != nil {
+		return nil, err
+	}
+
+	if m.Attachment != nil {
+		part, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {m.Attachment.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": m.Attachment.Name})},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := part.Write(wrap(base64.StdEncoding.EncodeToString(m.Attachment.Body))); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// ================================ Helper Functions
+
+// wrap splits encoded content into the 76 character lines required by MIME
+func wrap(s string) []byte {
+
+	var buf bytes.Buffer
+	for len(s) > 76 {
+		buf.WriteString(s[:76] + "\r\n")
+		s = s[76:]
+	}
+	buf.WriteString(s)
+
+	return buf.Bytes()
+}
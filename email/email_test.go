@@ -0,0 +1,106 @@
+package email
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// UnitSuite struct
+type UnitSuite struct {
+	suite.Suite
+}
+
+// TestRecipients method
+func (suite *UnitSuite) TestRecipients() {
+
+	installers := []string{"crew@example.com", "office@example.com"}
+
+	tests := []struct {
+		name       string
+		recipients []string
+		customer   string
+		want       []string
+	}{
+		{"default", nil, "jo@example.com", []string{"jo@example.com", "crew@example.com", "office@example.com"}},
+		{"default without customer email", nil, "", installers},
+		{"customer only", []string{RecipientCustomer}, "jo@example.com", []string{"jo@example.com"}},
+		{"address and duplicate", []string{"Crew@example.com", RecipientInstallers}, "", []string{"Crew@example.com", "office@example.com"}},
+	}
+
+	for _, tt := range tests {
+		suite.Equal(tt.want, Recipients(tt.recipients, tt.customer, installers), tt.name)
+	}
+}
+
+// TestRender method
+func (suite *UnitSuite) TestRender() {
+
+	subject, body, err := Render("", &TemplateData{CustomerName: "Jo Smith", FileName: "sht-1000.pdf", Number: 1000, URL: "https://example.com/sht"})
+	suite.NoError(err)
+	suite.Equal("Worksheet 1000 revision 0 for Jo Smith", subject)
+	suite.Contains(body, "https://example.com/sht")
+
+	_, body, err = Render(TemplateCustomer, &TemplateData{Attached: true, CustomerName: "Jo Smith", Number: 1000, URL: "https://example.com/sht"})
+	suite.NoError(err)
+	suite.Contains(body, "Attached")
+	suite.NotContains(body, "https://")
+
+	_, _, err = Render("memo", &TemplateData{})
+	suite.Error(err)
+}
+
+// TestBytes method
+func (suite *UnitSuite) TestBytes() {
+
+	pdf := bytes.Repeat([]byte("%PDF"), 100)
+	m := &Message{
+		Attachment: &Attachment{Body: pdf, ContentType: "application/pdf", Name: "sht-1000.pdf"},
+		Body:       "See attached",
+		From:       "worksheets@example.com",
+		Subject:    "Worksheet 1000",
+		To:         []string{"crew@example.com", "jo@example.com"},
+	}
+	raw, err := m.Bytes()
+	suite.NoError(err)
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	suite.NoError(err)
+	suite.Equal("Worksheet 1000", msg.Header.Get("Subject"))
+
+	to, err := msg.Header.AddressList("To")
+	suite.NoError(err)
+	suite.Len(to, 2)
+
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	suite.NoError(err)
+	r := multipart.NewReader(msg.Body, params["boundary"])
+
+	part, err := r.NextPart()
+	suite.NoError(err)
+	text, _ := ioutil.ReadAll(part)
+	suite.Equal("See attached", string(text))
+
+	part, err = r.NextPart()
+	suite.NoError(err)
+	suite.Equal("sht-1000.pdf", part.FileName())
+	enc, _ := ioutil.ReadAll(part)
+	for _, ln := range strings.Split(string(enc), "\r\n") {
+		suite.True(len(ln) <= 76)
+	}
+	dec, err := base64.StdEncoding.DecodeString(strings.Replace(string(enc), "\r\n", "", -1))
+	suite.NoError(err)
+	suite.Equal(pdf, dec)
+}
+
+// TestUnitSuite function
+func TestUnitSuite(t *testing.T) {
+	suite.Run(t, new(UnitSuite))
+}
@@ -0,0 +1,81 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Template constants
+const (
+	TemplateCustomer  = "customer"
+	TemplateInstaller = "installer"
+)
+
+// TemplateData struct
+// Values available to the templates
+type TemplateData struct {
+	Attached     bool
+	CustomerName string
+	Expires      time.Time
+	FileName     string
+	Number       int
+	Revision     int
+	URL          string
+}
+
+// the first line of each template is the subject
+var templates = map[string]*template.Template{
+	TemplateCustomer: template.Must(template.New(TemplateCustomer).Parse(`Your Universal Windows worksheet {{.Number}}
+Hello {{.CustomerName}},
+
+{{if .Attached}}Attached is the worksheet for quote {{.Number}}.{{else}}The worksheet for quote {{.Number}} is available at:
+
+{{.URL}}
+{{if not .Expires.IsZero}}
+This link expires {{.Expires.Format "January 2, 2006 3:04 PM MST"}}.{{end}}{{end}}
+
+Thank you,
+Universal Windows
+`)),
+	TemplateInstaller: template.Must(template.New(TemplateInstaller).Parse(`Worksheet {{.Number}} revision {{.Revision}} for {{.CustomerName}}
+{{if .Attached}}The worksheet {{.FileName}} is attached.{{else}}The worksheet {{.FileName}} is available at:
+
+{{.URL}}
+{{if not .Expires.IsZero}}
+This link expires {{.Expires.Format "January 2, 2006 3:04 PM MST"}}.{{end}}{{end}}
+`)),
+}
+
+// ValidTemplate function
+func ValidTemplate(name string) bool {
+	_, ok := templates[name]
+	return ok
+}
+
+// Render function
+// Returns the subject and body of the named template, the installer template is the default
+func Render(name string, data *TemplateData) (subject, body string, err error) {
+
+	if name == "" {
+		name = TemplateInstaller
+	}
+	t, ok := templates[name]
+	if !ok {
+		return "", "", fmt.Errorf("Unknown email template: %s", name)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", "", err
+	}
+
+	parts := strings.SplitN(buf.String(), "\n", 2)
+	if len(parts) < 2 {
+		return parts[0], "", nil
+	}
+
+	return parts[0], parts[1], nil
+}
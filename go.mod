@@ -6,16 +6,19 @@ require (
 	github.com/aws/aws-lambda-go v1.19.1
 	github.com/aws/aws-sdk-go v1.34.19
 	github.com/dustin/go-humanize v1.0.0
-	github.com/epsagon/epsagon-go v1.4.0
-	github.com/google/uuid v1.1.2 // indirect
+	github.com/golang-jwt/jwt/v4 v4.5.2
+	github.com/google/uuid v1.1.2
 	github.com/jung-kurt/gofpdf v1.16.2
-	github.com/pulpfree/lambda-go-auth v0.1.1
 	github.com/pulpfree/lambda-go-proxy-response v1.0.1
 	github.com/sirupsen/logrus v1.6.0
-	github.com/stretchr/testify v1.6.1
+	github.com/stretchr/testify v1.7.0
 	github.com/xdg/stringprep v1.0.0 // indirect
 	go.mongodb.org/mongo-driver v1.4.1
+	go.opentelemetry.io/otel v1.0.1
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp v1.0.1
+	go.opentelemetry.io/otel/exporters/stdout/stdouttrace v1.0.1
+	go.opentelemetry.io/otel/sdk v1.0.1
+	go.opentelemetry.io/otel/trace v1.0.1
 	golang.org/x/crypto v0.0.0-20200820211705-5c72a883971a // indirect
-	golang.org/x/sys v0.0.0-20200909081042-eff7692f9009 // indirect
 	gopkg.in/yaml.v2 v2.3.0
 )
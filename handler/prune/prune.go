@@ -0,0 +1,45 @@
+package main
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/pulpfree/univsales-wrksht-pdf/config"
+	"github.com/pulpfree/univsales-wrksht-pdf/logger"
+	"github.com/pulpfree/univsales-wrksht-pdf/metrics"
+	"github.com/pulpfree/univsales-wrksht-pdf/service"
+)
+
+var cfg *config.Config
+
+func init() {
+	cfg = &config.Config{}
+	err := cfg.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := logger.Configure(cfg); err != nil {
+		log.Fatal(err)
+	}
+	if err := metrics.Configure(cfg); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// HandleRequest function
+// Runs on a schedule to apply the worksheet version retention policy
+func HandleRequest(event events.CloudWatchEvent) error {
+
+	deleted, err := service.New(cfg, nil).PruneAll()
+	if err != nil {
+		return err
+	}
+	log.Infof("Pruned %d worksheet versions, keeping %d per quote", len(deleted), cfg.RetainVersions)
+
+	return nil
+}
+
+func main() {
+	lambda.Start(HandleRequest)
+}
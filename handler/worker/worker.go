@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/pulpfree/univsales-wrksht-pdf/config"
+	"github.com/pulpfree/univsales-wrksht-pdf/logger"
+	"github.com/pulpfree/univsales-wrksht-pdf/metrics"
+	"github.com/pulpfree/univsales-wrksht-pdf/model"
+	"github.com/pulpfree/univsales-wrksht-pdf/model/mongo"
+	"github.com/pulpfree/univsales-wrksht-pdf/service"
+	"github.com/pulpfree/univsales-wrksht-pdf/tracing"
+)
+
+// Set in init and shared by the invocations of a warm container
+var (
+	cfg  *config.Config
+	db   model.DBHandler
+	jobs model.JobStore
+)
+
+// Response struct
+// Lists the messages to redeliver, so SQS does not redeliver the jobs of the batch that succeeded.
+// The events package has no batch response type, and the event source needs ReportBatchItemFailures
+type Response struct {
+	BatchItemFailures []ItemFailure `json:"batchItemFailures"`
+}
+
+// ItemFailure struct
+type ItemFailure struct {
+	ItemIdentifier string `json:"itemIdentifier"`
+}
+
+func init() {
+	cfg = &config.Config{}
+	err := cfg.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := logger.Configure(cfg); err != nil {
+		log.Fatal(err)
+	}
+	if err := metrics.Configure(cfg); err != nil {
+		log.Fatal(err)
+	}
+	if err := tracing.Configure(cfg); err != nil {
+		log.Fatal(err)
+	}
+	db, err = mongo.NewDB(cfg.GetMongoConnectURL(), cfg.DBName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	jobs, err = mongo.NewJobStore(cfg.GetMongoConnectURL(), cfg.DBName)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// HandleRequest function
+// Consumes job ids from the queue and runs each job, reporting the messages of the jobs
+// that could not be run
+func HandleRequest(ctx context.Context, sqsEvent events.SQSEvent) (Response, error) {
+
+	defer tracing.Flush(ctx)
+
+	res := Response{BatchItemFailures: []ItemFailure{}}
+	svc := service.New(cfg, db).UseJobs(jobs, nil)
+	for _, msg := range sqsEvent.Records {
+		jobCtx, span := tracing.Start(ctx, "job", attribute.String(logger.FieldJobID, msg.Body))
+		l := log.WithField(logger.FieldJobID, msg.Body).WithContext(jobCtx)
+		l.Info("Running job")
+		err := svc.WithLogger(l).RunJob(msg.Body)
+		tracing.End(span, err)
+		if err != nil {
+			l.Errorf("Error running job: %s", err)
+			res.BatchItemFailures = append(res.BatchItemFailures, ItemFailure{ItemIdentifier: msg.MessageId})
+		}
+	}
+
+	return res, nil
+}
+
+func main() {
+	lambda.Start(HandleRequest)
+}
@@ -0,0 +1,110 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pulpfree/univsales-wrksht-pdf/config"
+	"github.com/pulpfree/univsales-wrksht-pdf/model"
+	"github.com/pulpfree/univsales-wrksht-pdf/storage"
+)
+
+// probeBody is the content of the storage probe
+var probeBody = []byte("ok")
+
+// Database function
+// Connects with db when there is no connection yet, then pings it
+func Database(db func() (model.DBHandler, error)) *Check {
+	return &Check{
+		Name: "mongo",
+		Run: func(ctx context.Context) error {
+			h, err := db()
+			if err != nil {
+				return err
+			}
+			return h.Ping(ctx)
+		},
+	}
+}
+
+// Storage function
+// Writes the probe key and reads it back, so a store that can be read but not written fails.
+// The probe is small and always the same key, so the unauthorized route cannot grow the store
+func Storage(store storage.Store) *Check {
+	return &Check{
+		Name: "storage",
+		Run: func(ctx context.Context) error {
+			if _, err := store.Put(storage.ProbeKey, probeBody, nil); err != nil {
+				return fmt.Errorf("put: %s", err)
+			}
+			body, err := store.Get(storage.ProbeKey)
+			if err != nil {
+				return fmt.Errorf("get: %s", err)
+			}
+			if !bytes.Equal(body, probeBody) {
+				return errors.New("get: probe body does not match")
+			}
+			return nil
+		},
+	}
+}
+
+// Logo function
+// Worksheets fail to render without the logo
+func Logo(uri string) *Check {
+	return &Check{
+		Name: "logo",
+		Run: func(ctx context.Context) error {
+			req, err := http.NewRequest(http.MethodHead, uri, nil)
+			if err != nil {
+				return err
+			}
+			res, err := http.DefaultClient.Do(req.WithContext(ctx))
+			if err != nil {
+				return err
+			}
+			res.Body.Close()
+			if res.StatusCode != http.StatusOK {
+				return fmt.Errorf("unexpected status: %s", res.Status)
+			}
+			if ct := res.Header.Get("Content-Type"); !strings.HasPrefix(ct, "image/") {
+				return fmt.Errorf("unexpected content type: %s", ct)
+			}
+			return nil
+		},
+	}
+}
+
+// Config function
+// Checks the settings that every request depends on are set
+func Config(cfg *config.Config) *Check {
+	return &Check{
+		Name: "config",
+		Run: func(ctx context.Context) error {
+			required := map[string]string{
+				"AWSRegion":    cfg.AWSRegion,
+				"DBConnectURL": cfg.GetMongoConnectURL(),
+				"DBName":       cfg.DBName,
+				"DocAuthor":    cfg.DocAuthor,
+				"LogoURI":      cfg.LogoURI,
+			}
+			if cfg.OutputDir == "" {
+				required["S3Bucket"] = cfg.S3Bucket
+			}
+			missing := []string{}
+			for _, k := range sortedKeys(required) {
+				if required[k] == "" {
+					missing = append(missing, k)
+				}
+			}
+			if len(missing) > 0 {
+				return fmt.Errorf("missing: %s", strings.Join(missing, ", "))
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,114 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Status constants
+const (
+	StatusDown = "down"
+	StatusFail = "fail"
+	StatusOK   = "ok"
+)
+
+// Check struct
+// Every check is of a dependency each request needs, so any failed check marks the service as down
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Result struct
+type Result struct {
+	Error     string `json:"error,omitempty"`
+	LatencyMS int64  `json:"latencyMs"`
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+}
+
+// Report struct
+type Report struct {
+	Checks []*Result `json:"checks"`
+	Status string    `json:"status"`
+}
+
+// Run function
+// Runs the checks concurrently, each limited to timeout, results are in the order of checks
+func Run(ctx context.Context, checks []*Check, timeout time.Duration) *Report {
+
+	rep := &Report{
+		Checks: make([]*Result, len(checks)),
+		Status: StatusOK,
+	}
+
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *Check) {
+			defer wg.Done()
+			rep.Checks[i] = run(ctx, c, timeout)
+		}(i, c)
+	}
+	wg.Wait()
+
+	for _, r := range rep.Checks {
+		if r.Status != StatusOK {
+			rep.Status = StatusDown
+			break
+		}
+	}
+
+	return rep
+}
+
+// ================================ Helper Functions
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// run returns once the check finishes or the timeout passes, whichever is first
+func run(ctx context.Context, c *Check, timeout time.Duration) *Result {
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	t := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				done <- errors.New("check panicked")
+			}
+		}()
+		done <- c.Run(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	r := &Result{
+		LatencyMS: time.Since(t).Milliseconds(),
+		Name:      c.Name,
+		Status:    StatusOK,
+	}
+	if err != nil {
+		r.Error = err.Error()
+		r.Status = StatusFail
+	}
+
+	return r
+}
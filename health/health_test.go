@@ -0,0 +1,100 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pulpfree/univsales-wrksht-pdf/storage"
+	"github.com/stretchr/testify/suite"
+)
+
+// UnitSuite struct
+type UnitSuite struct {
+	suite.Suite
+}
+
+func check(name string, err error) *Check {
+	return &Check{
+		Name: name,
+		Run:  func(ctx context.Context) error { return err },
+	}
+}
+
+// TestRun method
+func (suite *UnitSuite) TestRun() {
+
+	tests := []struct {
+		name   string
+		checks []*Check
+		status string
+	}{
+		{"all ok", []*Check{check("a", nil), check("b", nil)}, StatusOK},
+		{"one failure", []*Check{check("a", nil), check("b", errors.New("x"))}, StatusDown},
+		{"all failed", []*Check{check("a", errors.New("x")), check("b", errors.New("x"))}, StatusDown},
+	}
+
+	for _, tt := range tests {
+		rep := Run(context.Background(), tt.checks, time.Second)
+		suite.Equal(tt.status, rep.Status, tt.name)
+		suite.Len(rep.Checks, len(tt.checks), tt.name)
+		suite.Equal("a", rep.Checks[0].Name, tt.name)
+	}
+}
+
+// TestTimeout method
+func (suite *UnitSuite) TestTimeout() {
+
+	slow := &Check{
+		Name: "slow",
+		Run: func(ctx context.Context) error {
+			time.Sleep(time.Second)
+			return nil
+		},
+	}
+
+	rep := Run(context.Background(), []*Check{slow}, 20*time.Millisecond)
+	suite.Equal(StatusDown, rep.Status)
+	suite.Equal(StatusFail, rep.Checks[0].Status)
+	suite.Equal(context.DeadlineExceeded.Error(), rep.Checks[0].Error)
+	suite.True(rep.Checks[0].LatencyMS < 1000)
+}
+
+// TestStorage method
+// The storage check writes and reads back the one probe key
+func (suite *UnitSuite) TestStorage() {
+
+	dir, err := ioutil.TempDir("", "health")
+	suite.NoError(err)
+	defer os.RemoveAll(dir)
+
+	store := storage.NewLocal(dir, "")
+	suite.NoError(Storage(store).Run(context.Background()))
+	suite.NoError(Storage(store).Run(context.Background()))
+
+	files, err := store.List("")
+	suite.NoError(err)
+	suite.Len(files, 1)
+	suite.Equal(storage.ProbeKey, files[0].Key)
+
+	// a store that lists and reads but cannot write fails
+	suite.EqualError(Storage(&readOnly{store}).Run(context.Background()), "put: read only")
+}
+
+// ================================ Helper Methods
+
+type readOnly struct {
+	storage.Store
+}
+
+func (s *readOnly) Put(string, []byte, map[string]string) (*storage.FileInfo, error) {
+	return nil, errors.New("read only")
+}
+
+// TestUnitSuite function
+func TestUnitSuite(t *testing.T) {
+	suite.Run(t, new(UnitSuite))
+}
@@ -0,0 +1,127 @@
+package logger
+
+import (
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/pulpfree/univsales-wrksht-pdf/cognito"
+	"github.com/pulpfree/univsales-wrksht-pdf/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// Field name constants
+const (
+	FieldCaller    = "caller"
+	FieldEmail     = "email"
+	FieldGroups    = "groups"
+	FieldJobID     = "job_id"
+	FieldPrincipal = "principal"
+	FieldQuoteID   = "quote_id"
+	FieldRequestID = "request_id"
+	FieldStage     = "stage"
+	FieldUserID    = "user_id"
+)
+
+// Redacted replaces the value of redacted fields
+const Redacted = "[REDACTED]"
+
+// Configure function
+// Sets the standard logger to emit JSON at the configured level, redacting the configured fields.
+// Called once at start up, before any request is handled
+func Configure(cfg *config.Config) error {
+
+	lvl, err := log.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		return err
+	}
+	log.SetLevel(lvl)
+	log.SetFormatter(NewRedactor(cfg.LogRedact, &log.JSONFormatter{}))
+
+	return nil
+}
+
+// Default function
+// Returns an entry of the standard logger, used where no request logger is set
+func Default() *log.Entry {
+	return log.NewEntry(log.StandardLogger())
+}
+
+// ForRequest function
+// Returns an entry tagged with the api gateway request id, the authorizer principal and identity, and the stage.
+// The email is subject to the redaction policy like any other field
+func ForRequest(req events.APIGatewayProxyRequest, cfg *config.Config) *log.Entry {
+
+	principal, _ := req.RequestContext.Authorizer["principalId"].(string)
+
+	fields := log.Fields{
+		FieldPrincipal: principal,
+		FieldRequestID: req.RequestContext.RequestID,
+		FieldStage:     string(cfg.GetStageEnv()),
+	}
+	if id := cognito.IdentityFromContext(req.RequestContext.Authorizer); id != nil {
+		fields[FieldCaller] = id.Caller
+		fields[FieldEmail] = id.Email
+		fields[FieldGroups] = id.Groups
+		fields[FieldUserID] = id.UserID
+	}
+
+	return Default().WithFields(fields)
+}
+
+// Redactor struct
+// A formatter replacing the values of the policy fields, matched case insensitively at any depth
+type Redactor struct {
+	fields map[string]bool
+	next   log.Formatter
+}
+
+// NewRedactor function
+func NewRedactor(fields []string, next log.Formatter) *Redactor {
+
+	r := &Redactor{fields: map[string]bool{}, next: next}
+	for _, f := range fields {
+		r.fields[strings.ToLower(f)] = true
+	}
+
+	return r
+}
+
+// Format method
+func (r *Redactor) Format(e *log.Entry) ([]byte, error) {
+
+	data := make(log.Fields, len(e.Data))
+	for k, v := range e.Data {
+		data[k] = r.redact(k, v)
+	}
+
+	c := *e
+	c.Data = data
+
+	return r.next.Format(&c)
+}
+
+// ================================ Helper Methods
+
+func (r *Redactor) redact(key string, v interface{}) interface{} {
+
+	if r.fields[strings.ToLower(key)] {
+		return Redacted
+	}
+
+	switch m := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(m))
+		for k, mv := range m {
+			c[k] = r.redact(k, mv)
+		}
+		return c
+	case map[string]string:
+		c := make(map[string]interface{}, len(m))
+		for k, mv := range m {
+			c[k] = r.redact(k, mv)
+		}
+		return c
+	}
+
+	return v
+}
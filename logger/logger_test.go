@@ -0,0 +1,97 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/pulpfree/univsales-wrksht-pdf/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+)
+
+// LoggerSuite struct
+type LoggerSuite struct {
+	suite.Suite
+	buf *bytes.Buffer
+	l   *log.Logger
+}
+
+// SetupTest method
+func (suite *LoggerSuite) SetupTest() {
+	suite.buf = &bytes.Buffer{}
+	suite.l = log.New()
+	suite.l.SetOutput(suite.buf)
+	suite.l.SetFormatter(NewRedactor([]string{"customer", "Total"}, &log.JSONFormatter{}))
+}
+
+// TestRedact method
+func (suite *LoggerSuite) TestRedact() {
+
+	suite.l.WithFields(log.Fields{
+		"customer": "Jane Doe",
+		"quote_id": "5ccc90913c4a256251cf326b",
+		"TOTAL":    1250.5,
+	}).Info("rendered")
+
+	out := suite.decode()
+	suite.Equal(Redacted, out["customer"])
+	suite.Equal(Redacted, out["TOTAL"])
+	suite.Equal("5ccc90913c4a256251cf326b", out["quote_id"])
+	suite.Equal("rendered", out["msg"])
+}
+
+// TestRedactNested method
+func (suite *LoggerSuite) TestRedactNested() {
+
+	suite.l.WithField("quote", map[string]interface{}{
+		"number": 1001,
+		"fees":   map[string]interface{}{"total": 99.0, "discount": 5.0},
+	}).Info("nested")
+
+	q, ok := suite.decode()["quote"].(map[string]interface{})
+	suite.True(ok)
+	suite.Equal(float64(1001), q["number"])
+	fees, ok := q["fees"].(map[string]interface{})
+	suite.True(ok)
+	suite.Equal(Redacted, fees["total"])
+	suite.Equal(5.0, fees["discount"])
+}
+
+// TestEntryUnchanged method
+func (suite *LoggerSuite) TestEntryUnchanged() {
+
+	e := suite.l.WithField("customer", "Jane Doe")
+	e.Info("first")
+	suite.Equal("Jane Doe", e.Data["customer"])
+}
+
+// TestForRequest method
+func (suite *LoggerSuite) TestForRequest() {
+
+	cfg := &config.Config{}
+	cfg.SetStageEnv("test")
+
+	req := events.APIGatewayProxyRequest{}
+	req.RequestContext.RequestID = "req-1"
+	req.RequestContext.Authorizer = map[string]interface{}{"principalId": "user-1"}
+
+	e := ForRequest(req, cfg)
+	suite.Equal("req-1", e.Data[FieldRequestID])
+	suite.Equal("user-1", e.Data[FieldPrincipal])
+	suite.Equal("test", e.Data[FieldStage])
+}
+
+// ================================ Helper Methods
+
+func (suite *LoggerSuite) decode() map[string]interface{} {
+	out := map[string]interface{}{}
+	suite.NoError(json.Unmarshal(suite.buf.Bytes(), &out))
+	return out
+}
+
+// TestLoggerSuite function
+func TestLoggerSuite(t *testing.T) {
+	suite.Run(t, new(LoggerSuite))
+}
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// EMF struct
+// Writes each measurement as a CloudWatch Embedded Metric Format line, which Lambda
+// extracts from the function output without any api calls
+type EMF struct {
+	dims      Dims
+	mu        sync.Mutex
+	namespace string
+	w         io.Writer
+}
+
+// NewEMF function
+func NewEMF(w io.Writer, namespace string, dims Dims) *EMF {
+	return &EMF{dims: dims, namespace: namespace, w: w}
+}
+
+type emfMetric struct {
+	Name string `json:"Name"`
+	Unit Unit   `json:"Unit"`
+}
+
+type emfDirective struct {
+	Dimensions [][]string  `json:"Dimensions"`
+	Metrics    []emfMetric `json:"Metrics"`
+	Namespace  string      `json:"Namespace"`
+}
+
+type emfMeta struct {
+	CloudWatchMetrics []emfDirective `json:"CloudWatchMetrics"`
+	Timestamp         int64          `json:"Timestamp"`
+}
+
+// Record method
+func (e *EMF) Record(name string, value float64, unit Unit, dims Dims) {
+
+	all := merge(e.dims, dims)
+	keys := make([]string, 0, len(all))
+	for k := range all {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	line := make(map[string]interface{}, len(all)+2)
+	for k, v := range all {
+		line[k] = v
+	}
+	line[name] = value
+	line["_aws"] = emfMeta{
+		CloudWatchMetrics: []emfDirective{{
+			Dimensions: [][]string{keys},
+			Metrics:    []emfMetric{{Name: name, Unit: unit}},
+			Namespace:  e.namespace,
+		}},
+		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+	}
+
+	body, err := json.Marshal(line)
+	if err != nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.w.Write(append(body, '\n'))
+}
@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/pulpfree/univsales-wrksht-pdf/config"
+)
+
+// Sink constants, the Metrics config values
+const (
+	SinkEMF        = "emf"
+	SinkNone       = "none"
+	SinkPrometheus = "prometheus"
+)
+
+// Metric name constants
+const (
+	FetchDuration  = "fetch_duration"
+	RenderDuration = "render_duration"
+	RenderPages    = "render_pages"
+	UploadDuration = "upload_duration"
+	UploadSize     = "upload_size"
+)
+
+// Dimension name constants
+const (
+	DimStage = "Stage"
+	DimStep  = "Step"
+)
+
+// Unit type
+type Unit string
+
+// Unit constants, named as CloudWatch units
+const (
+	Bytes        Unit = "Bytes"
+	Count        Unit = "Count"
+	Milliseconds Unit = "Milliseconds"
+)
+
+// Dims type
+// Dimension names and values of a single measurement
+type Dims map[string]string
+
+// Recorder interface
+type Recorder interface {
+	Record(name string, value float64, unit Unit, dims Dims)
+}
+
+// std is the recorder returned by Default, measurements are dropped until Configure is called
+var std Recorder = Nop{}
+
+// Configure function
+// Sets the default recorder to the configured sink, every measurement carries the stage dimension.
+// Called once at start up, before any request is handled
+func Configure(cfg *config.Config) error {
+
+	dims := Dims{DimStage: string(cfg.GetStageEnv())}
+
+	switch cfg.Metrics {
+	case SinkEMF:
+		std = NewEMF(os.Stdout, cfg.MetricsNamespace, dims)
+	case SinkPrometheus:
+		std = NewPrometheus(dims)
+	case SinkNone, "":
+		std = Nop{}
+	default:
+		return fmt.Errorf("Invalid Metrics value: %s", cfg.Metrics)
+	}
+
+	return nil
+}
+
+// Default function
+func Default() Recorder {
+	return std
+}
+
+// Handler function
+// Returns the default recorder when it serves its measurements over http, as the Prometheus recorder does
+func Handler() http.Handler {
+	if h, ok := std.(http.Handler); ok {
+		return h
+	}
+	return http.NotFoundHandler()
+}
+
+// Since function
+// Records the milliseconds elapsed from start
+func Since(r Recorder, name string, start time.Time, dims Dims) {
+	r.Record(name, float64(time.Since(start).Microseconds())/1000, Milliseconds, dims)
+}
+
+// Nop struct
+// Drops every measurement
+type Nop struct{}
+
+// Record method
+func (Nop) Record(name string, value float64, unit Unit, dims Dims) {}
+
+// ================================ Helper Functions
+
+// merge returns the base dimensions overridden by dims
+func merge(base, dims Dims) Dims {
+
+	m := make(Dims, len(base)+len(dims))
+	for k, v := range base {
+		m[k] = v
+	}
+	for k, v := range dims {
+		m[k] = v
+	}
+
+	return m
+}
@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pulpfree/univsales-wrksht-pdf/config"
+	"github.com/stretchr/testify/suite"
+)
+
+// MetricsSuite struct
+type MetricsSuite struct {
+	suite.Suite
+}
+
+// TestEMF method
+func (suite *MetricsSuite) TestEMF() {
+
+	buf := &bytes.Buffer{}
+	e := NewEMF(buf, "UnivSales/WrkshtPDF", Dims{DimStage: "prod"})
+	e.Record(FetchDuration, 12.5, Milliseconds, Dims{DimStep: "quote"})
+
+	line := struct {
+		AWS struct {
+			CloudWatchMetrics []struct {
+				Dimensions [][]string
+				Metrics    []struct{ Name, Unit string }
+				Namespace  string
+			}
+			Timestamp int64
+		} `json:"_aws"`
+		FetchDuration float64 `json:"fetch_duration"`
+		Stage         string
+		Step          string
+	}{}
+	suite.NoError(json.Unmarshal(buf.Bytes(), &line))
+
+	suite.Equal(12.5, line.FetchDuration)
+	suite.Equal("prod", line.Stage)
+	suite.Equal("quote", line.Step)
+	suite.True(line.AWS.Timestamp > 0)
+	suite.Len(line.AWS.CloudWatchMetrics, 1)
+	d := line.AWS.CloudWatchMetrics[0]
+	suite.Equal("UnivSales/WrkshtPDF", d.Namespace)
+	suite.Equal([][]string{{DimStage, DimStep}}, d.Dimensions)
+	suite.Equal(FetchDuration, d.Metrics[0].Name)
+	suite.Equal(string(Milliseconds), d.Metrics[0].Unit)
+}
+
+// TestPrometheus method
+func (suite *MetricsSuite) TestPrometheus() {
+
+	p := NewPrometheus(Dims{DimStage: "test"})
+	p.Record(FetchDuration, 10, Milliseconds, Dims{DimStep: "quote"})
+	p.Record(FetchDuration, 5, Milliseconds, Dims{DimStep: "quote"})
+	p.Record(UploadSize, 2048, Bytes, nil)
+	p.Record(RenderPages, 2, Count, nil)
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	expect := `# TYPE wrksht_fetch_duration_milliseconds summary
+wrksht_fetch_duration_milliseconds_sum{stage="test",step="quote"} 15
+wrksht_fetch_duration_milliseconds_count{stage="test",step="quote"} 2
+# TYPE wrksht_render_pages summary
+wrksht_render_pages_sum{stage="test"} 2
+wrksht_render_pages_count{stage="test"} 1
+# TYPE wrksht_upload_size_bytes summary
+wrksht_upload_size_bytes_sum{stage="test"} 2048
+wrksht_upload_size_bytes_count{stage="test"} 1
+`
+	suite.Equal(expect, w.Body.String())
+}
+
+// TestConfigure method
+func (suite *MetricsSuite) TestConfigure() {
+
+	defer func() { std = Nop{} }()
+
+	cfg := &config.Config{}
+	cfg.SetStageEnv("test")
+
+	cfg.Metrics = SinkPrometheus
+	suite.NoError(Configure(cfg))
+	suite.IsType(&Prometheus{}, Default())
+	suite.IsType(&Prometheus{}, Handler())
+
+	cfg.Metrics = SinkEMF
+	suite.NoError(Configure(cfg))
+	suite.IsType(&EMF{}, Default())
+
+	cfg.Metrics = SinkNone
+	suite.NoError(Configure(cfg))
+	suite.IsType(Nop{}, Default())
+
+	cfg.Metrics = "statsd"
+	suite.Error(Configure(cfg))
+}
+
+// TestMetricsSuite function
+func TestMetricsSuite(t *testing.T) {
+	suite.Run(t, new(MetricsSuite))
+}
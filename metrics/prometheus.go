@@ -0,0 +1,115 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// namePrefix is prepended to every Prometheus metric name
+const namePrefix = "wrksht_"
+
+// Prometheus struct
+// Keeps a running count and sum of each measurement, served in the Prometheus text format
+type Prometheus struct {
+	dims   Dims
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	count  int64
+	labels string
+	name   string
+	sum    float64
+}
+
+// NewPrometheus function
+func NewPrometheus(dims Dims) *Prometheus {
+	return &Prometheus{dims: dims, series: map[string]*series{}}
+}
+
+// Record method
+func (p *Prometheus) Record(name string, value float64, unit Unit, dims Dims) {
+
+	name = promName(name, unit)
+	labels := promLabels(merge(p.dims, dims))
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s, ok := p.series[name+labels]
+	if !ok {
+		s = &series{labels: labels, name: name}
+		p.series[name+labels] = s
+	}
+	s.count++
+	s.sum += value
+}
+
+// ServeHTTP method
+// Writes each metric as a summary of its sum and count
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	p.mu.Lock()
+	all := make([]series, 0, len(p.series))
+	for _, s := range p.series {
+		all = append(all, *s)
+	}
+	p.mu.Unlock()
+
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].name != all[j].name {
+			return all[i].name < all[j].name
+		}
+		return all[i].labels < all[j].labels
+	})
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	last := ""
+	for _, s := range all {
+		if s.name != last {
+			fmt.Fprintf(w, "# TYPE %s summary\n", s.name)
+			last = s.name
+		}
+		fmt.Fprintf(w, "%s_sum%s %s\n", s.name, s.labels, strconv.FormatFloat(s.sum, 'g', -1, 64))
+		fmt.Fprintf(w, "%s_count%s %d\n", s.name, s.labels, s.count)
+	}
+}
+
+// ================================ Helper Functions
+
+// promName adds the prefix and the base unit suffix Prometheus names carry
+func promName(name string, unit Unit) string {
+	switch unit {
+	case Bytes:
+		return namePrefix + name + "_bytes"
+	case Milliseconds:
+		return namePrefix + name + "_milliseconds"
+	}
+	return namePrefix + name
+}
+
+// promLabels returns the dimensions as a sorted label set, such as {stage="prod",step="quote"}
+func promLabels(dims Dims) string {
+
+	if len(dims) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(dims))
+	for k := range dims {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = strings.ToLower(k) + "=" + strconv.Quote(dims[k])
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
@@ -1,7 +1,34 @@
 package model
 
+import (
+	"context"
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Errors returned by the DBHandler and JobStore implementations
+var (
+	ErrInvalidID     = errors.New("Invalid id")
+	ErrJobNotFound   = errors.New("Job not found")
+	ErrQuoteNotFound = errors.New("Quote not found")
+)
+
 // DBHandler interface
 type DBHandler interface {
 	Close()
 	FetchQuote(string) (*Quote, error)
+	FetchQuoteIDByNumber(int) (string, error)
+	FetchQuoteIDs(start, end time.Time) ([]string, error)
+	Ping(ctx context.Context) error
+	WithLogger(*log.Entry) DBHandler
+}
+
+// JobStore interface
+type JobStore interface {
+	Close()
+	CreateJob(*Job) error
+	FetchJob(string) (*Job, error)
+	UpdateJob(*Job) error
 }
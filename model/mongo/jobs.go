@@ -0,0 +1,103 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pulpfree/univsales-wrksht-pdf/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// JobDB struct
+type JobDB struct {
+	client *mongo.Client
+	col    *mongo.Collection
+}
+
+// NewJobStore sets up new JobDB struct
+func NewJobStore(connection string, dbNm string) (model.JobStore, error) {
+
+	client, err := connect(connection)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JobDB{
+		client: client,
+		col:    client.Database(dbNm).Collection(colJobs),
+	}, nil
+}
+
+// CreateJob method
+func (db *JobDB) CreateJob(j *model.Job) error {
+
+	now := time.Now()
+	j.ID = primitive.NewObjectID()
+	j.CreatedAt = now
+	j.UpdatedAt = now
+	if j.Status == "" {
+		j.Status = model.JobQueued
+	}
+
+	_, err := db.col.InsertOne(context.Background(), j)
+	return err
+}
+
+// FetchJob method
+func (db *JobDB) FetchJob(jobID string) (*model.Job, error) {
+
+	objectID, err := primitive.ObjectIDFromHex(jobID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: jobID %s", model.ErrInvalidID, jobID)
+	}
+
+	j := &model.Job{}
+	filter := bson.D{primitive.E{Key: "_id", Value: objectID}}
+	err = db.col.FindOne(context.Background(), filter).Decode(j)
+	if err == mongo.ErrNoDocuments {
+		return nil, model.ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return j, nil
+}
+
+// UpdateJob method
+func (db *JobDB) UpdateJob(j *model.Job) error {
+
+	j.UpdatedAt = time.Now()
+	filter := bson.D{primitive.E{Key: "_id", Value: j.ID}}
+	update := bson.D{primitive.E{Key: "$set", Value: bson.D{
+		primitive.E{Key: "error", Value: j.Error},
+		primitive.E{Key: "failures", Value: j.Failures},
+		primitive.E{Key: "key", Value: j.Key},
+		primitive.E{Key: "status", Value: j.Status},
+		primitive.E{Key: "updatedAt", Value: j.UpdatedAt},
+	}}}
+
+	res, err := db.col.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("%w: %s", model.ErrJobNotFound, j.ID.Hex())
+	}
+
+	return nil
+}
+
+// Close method
+func (db *JobDB) Close() {
+	err := db.client.Disconnect(context.Background())
+	if err != nil {
+		log.Error(err)
+	}
+}
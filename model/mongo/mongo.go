@@ -4,13 +4,19 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
+	"github.com/pulpfree/univsales-wrksht-pdf/metrics"
 	"github.com/pulpfree/univsales-wrksht-pdf/model"
+	"github.com/pulpfree/univsales-wrksht-pdf/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -20,6 +26,7 @@ const (
 	colAddress    = "addresses"
 	colCustomer   = "customers"
 	colGroupTypes = "group-types"
+	colJobs       = "wrksht-jobs"
 	colJS         = "jobsheets"
 	colJSGroups   = "jobsheet-win-grps"
 	colJSOther    = "jobsheet-other"
@@ -32,85 +39,147 @@ const (
 
 // MDB struct
 type MDB struct {
-	client *mongo.Client
-	dbName string
-	db     *mongo.Database
+	client  *mongo.Client
+	dbName  string
+	db      *mongo.Database
+	log     *log.Entry
+	metrics metrics.Recorder
 }
 
 // NewDB sets up new MDB struct
 func NewDB(connection string, dbNm string) (model.DBHandler, error) {
 
+	client, err := connect(connection)
+	if err != nil {
+		return nil, err
+	}
+
+	// defer suite.db.Close()
+
+	return &MDB{
+		client:  client,
+		dbName:  dbNm,
+		db:      client.Database(dbNm),
+		log:     log.NewEntry(log.StandardLogger()),
+		metrics: metrics.Default(),
+	}, err
+}
+
+// connect creates and checks a new client connection
+func connect(connection string) (*mongo.Client, error) {
+
 	clientOptions := options.Client().ApplyURI(connection)
 	err := clientOptions.Validate()
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	// Connect to MongoDB
 	client, err := mongo.Connect(context.Background(), clientOptions)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	// Check the connection
 	err = client.Ping(context.Background(), nil)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	log.Println("Connected to MongoDB!")
-
-	// defer suite.db.Close()
+	log.Info("Connected to MongoDB")
 
-	return &MDB{
-		client: client,
-		dbName: dbNm,
-		db:     client.Database(dbNm),
-	}, err
+	return client, nil
 }
 
 // FetchQuote method
+// Records the duration of each fetch step, and of the whole fetch as the total step
 func (db *MDB) FetchQuote(quoteID string) (*model.Quote, error) {
 
 	// Initialize
 	q := &model.Quote{}
+	start := time.Now()
+	defer db.since("total", start)
 
 	// Fetch quote
 	err := db.getQuote(q, quoteID)
+	db.since("quote", start)
 	if err != nil {
 		return q, err
 	}
 
-	// Fetch Group items
-	err = db.getGroupItems(q)
-	if err != nil {
-		return q, err
+	steps := []struct {
+		name string
+		fn   func(*model.Quote) error
+	}{
+		{"groups", db.getGroupItems},
+		{"windows", db.getWindowItems},
+		{"other", db.getOtherItems},
+		{"features", db.getJobsheetFeatures},
+		{"customer", db.getCustomer},
 	}
+	for _, s := range steps {
+		t := time.Now()
+		err = s.fn(q)
+		db.since(s.name, t)
+		if err != nil {
+			return q, err
+		}
+	}
+
+	return q, nil
+}
 
-	// Fetch Windows
-	err = db.getWindowItems(q)
+// FetchQuoteIDByNumber method
+// Returns the id of the latest revision of the quote with number
+func (db *MDB) FetchQuoteIDByNumber(number int) (string, error) {
+
+	q := &model.Quote{}
+	filter := bson.D{primitive.E{Key: "number", Value: number}}
+	opts := options.FindOne().SetProjection(bson.M{"_id": 1}).SetSort(bson.M{"version": -1})
+	err := db.findOne(colQuotes, filter, q, opts)
+	if err == mongo.ErrNoDocuments {
+		return "", fmt.Errorf("%w: number %d", model.ErrQuoteNotFound, number)
+	}
 	if err != nil {
-		return q, err
+		return "", err
 	}
 
-	// Fetch Other items
-	err = db.getOtherItems(q)
-	if err != nil {
-		return q, err
+	return q.ID.Hex(), nil
+}
+
+// FetchQuoteIDs method
+// Returns the ids of quotes belonging to jobsheets created within the start and end dates
+func (db *MDB) FetchQuoteIDs(start, end time.Time) ([]string, error) {
+
+	jsFilter := bson.D{primitive.E{Key: "createdAt", Value: bson.D{
+		primitive.E{Key: "$gte", Value: start},
+		primitive.E{Key: "$lt", Value: end},
+	}}}
+	jobSheets := []*model.JobSheet{}
+	if err := db.find(colJS, jsFilter, &jobSheets, options.Find().SetProjection(bson.M{"_id": 1})); err != nil {
+		return nil, err
+	}
+	if len(jobSheets) == 0 {
+		return []string{}, nil
 	}
 
-	// Fetch Jobsheet features
-	err = db.getJobsheetFeatures(q)
-	if err != nil {
-		return q, err
+	jsIDs := make([]primitive.ObjectID, len(jobSheets))
+	for i, js := range jobSheets {
+		jsIDs[i] = js.ID
 	}
 
-	// Fetch customer data
-	err = db.getCustomer(q)
-	if err != nil {
-		return q, err
+	qFilter := bson.D{primitive.E{Key: "jobsheetID", Value: bson.D{primitive.E{Key: "$in", Value: jsIDs}}}}
+	opts := options.Find().SetProjection(bson.M{"_id": 1}).SetSort(bson.M{"number": 1})
+	quotes := []*model.Quote{}
+	if err := db.find(colQuotes, qFilter, &quotes, opts); err != nil {
+		return nil, err
 	}
 
-	return q, nil
+	ids := make([]string, len(quotes))
+	for i, q := range quotes {
+		ids[i] = q.ID.Hex()
+	}
+
+	return ids, nil
 }
 
 func (db *MDB) getQuote(q *model.Quote, quoteID string) error {
@@ -119,14 +188,19 @@ func (db *MDB) getQuote(q *model.Quote, quoteID string) error {
 		return errors.New("Missing quoteID string")
 	}
 
-	col := db.db.Collection(colQuotes)
 	objectIDS, err := primitive.ObjectIDFromHex(quoteID)
+	if err != nil {
+		return fmt.Errorf("%w: quoteID %s", model.ErrInvalidID, quoteID)
+	}
 	// filter := bson.D{{"_id", objectIDS}}
 	// found answer to go-vet issue in above filter here: https://stackoverflow.com/questions/54548441/composite-literal-uses-unkeyed-fields#answer-54548495
 	filter := bson.D{primitive.E{Key: "_id", Value: objectIDS}}
-	err = col.FindOne(context.Background(), filter).Decode(&q)
+	err = db.findOne(colQuotes, filter, &q)
+	if err == mongo.ErrNoDocuments {
+		return fmt.Errorf("%w: %s", model.ErrQuoteNotFound, quoteID)
+	}
 	if err != nil {
-		log.Fatalf("quote table error: %s", err)
+		db.log.Errorf("quote table error: %s", err)
 		return err
 	}
 
@@ -138,7 +212,6 @@ func (db *MDB) getGroupItems(q *model.Quote) error {
 	// fixme: this should be in FetchQuote
 	q.Items = &model.Items{}
 
-	col := db.db.Collection(colJSGroups)
 	for _, i := range q.ItemIds.Group {
 		item := &model.Group{}
 		// these IDs were stored as strings, so now need to convert
@@ -148,8 +221,8 @@ func (db *MDB) getGroupItems(q *model.Quote) error {
 		}
 
 		filter := bson.D{primitive.E{Key: "_id", Value: objectIDS}}
-		if err := col.FindOne(context.Background(), filter).Decode(&item); err != nil {
-			log.Fatalf("Group not found. Error: %s, group id: %s", err, i)
+		if err := db.findOne(colJSGroups, filter, &item); err != nil {
+			db.log.Errorf("Group not found. Error: %s, group id: %s", err, i)
 			return err
 		}
 		// Fetch group type
@@ -170,7 +243,6 @@ func (db *MDB) getGroupItems(q *model.Quote) error {
 
 func (db *MDB) getWindowItems(q *model.Quote) error {
 
-	col := db.db.Collection(colJSWindows)
 	for _, i := range q.ItemIds.Window {
 		item := &model.Window{}
 		objectIDS, err := primitive.ObjectIDFromHex(i)
@@ -179,8 +251,8 @@ func (db *MDB) getWindowItems(q *model.Quote) error {
 		}
 
 		filter := bson.D{primitive.E{Key: "_id", Value: objectIDS}}
-		if err := col.FindOne(context.Background(), filter).Decode(&item); err != nil {
-			log.Fatalf("Window error for id: %s. Error: %s", i, err)
+		if err := db.findOne(colJSWindows, filter, &item); err != nil {
+			db.log.Errorf("Window error for id: %s. Error: %s", i, err)
 			return err
 		}
 		// fetch product info
@@ -197,7 +269,6 @@ func (db *MDB) getWindowItems(q *model.Quote) error {
 
 func (db *MDB) getOtherItems(q *model.Quote) error {
 
-	col := db.db.Collection(colJSOther)
 	for _, i := range q.ItemIds.Other {
 		item := &model.Other{}
 		objectIDS, err := primitive.ObjectIDFromHex(i)
@@ -206,8 +277,8 @@ func (db *MDB) getOtherItems(q *model.Quote) error {
 		}
 
 		filter := bson.D{primitive.E{Key: "_id", Value: objectIDS}}
-		if err := col.FindOne(context.Background(), filter).Decode(&item); err != nil {
-			log.Fatalf("Other not found. Error: %s", err)
+		if err := db.findOne(colJSOther, filter, &item); err != nil {
+			db.log.Errorf("Other not found. Error: %s", err)
 			return err
 		}
 		q.Items.Other = append(q.Items.Other, item)
@@ -218,10 +289,9 @@ func (db *MDB) getOtherItems(q *model.Quote) error {
 
 func (db *MDB) getProductName(productID primitive.ObjectID) (*model.Product, error) {
 
-	col := db.db.Collection(colProducts)
 	p := &model.Product{}
 	filter := bson.D{primitive.E{Key: "_id", Value: productID}}
-	if err := col.FindOne(context.Background(), filter).Decode(&p); err != nil {
+	if err := db.findOne(colProducts, filter, &p); err != nil {
 		return p, err
 	}
 	return p, nil
@@ -229,9 +299,8 @@ func (db *MDB) getProductName(productID primitive.ObjectID) (*model.Product, err
 
 func (db *MDB) getCustomer(q *model.Quote) error {
 
-	col := db.db.Collection(colCustomer)
 	filter := bson.D{primitive.E{Key: "_id", Value: q.CustomerID}}
-	err := col.FindOne(context.Background(), filter).Decode(&q.Customer)
+	err := db.findOne(colCustomer, filter, &q.Customer)
 	if err != nil {
 		return err
 	}
@@ -240,12 +309,15 @@ func (db *MDB) getCustomer(q *model.Quote) error {
 		phoneMap[v.Type] = v.Number
 	}
 	q.Customer.PhoneMap = phoneMap
+	// older jobsheets have no branch, the customer's branch stands in
+	if q.Branch == "" {
+		q.Branch = q.Customer.Branch
+	}
 
 	// // Fetch customer address data
-	col = db.db.Collection(colAddress)
 	adFilter := bson.D{primitive.E{Key: "customerID", Value: q.CustomerID}, primitive.E{Key: "associate", Value: "customer"}}
 	// err = col.Find(bson.M{"customerID": q.CustomerID, "associate": "customer"}).One(&q.Customer.Address)
-	err = col.FindOne(context.Background(), adFilter).Decode(&q.Customer.Address)
+	err = db.findOne(colAddress, adFilter, &q.Customer.Address)
 	if err != nil {
 		return err
 	}
@@ -255,7 +327,6 @@ func (db *MDB) getCustomer(q *model.Quote) error {
 
 func (db *MDB) getJobsheetFeatures(q *model.Quote) error {
 
-	col := db.db.Collection(colJS)
 	jobSheet := &model.JobSheet{}
 
 	// see: https://stackoverflow.com/questions/53120116/how-to-filter-fields-from-a-mongo-document-with-the-official-mongo-go-driver
@@ -265,20 +336,79 @@ func (db *MDB) getJobsheetFeatures(q *model.Quote) error {
 	} */
 
 	filter := bson.D{primitive.E{Key: "_id", Value: q.JobsheetID}}
-	if err := col.FindOne(context.Background(), filter).Decode(&jobSheet); err != nil {
-		log.Fatalf("Jobsheet query error: %s", err)
+	if err := db.findOne(colJS, filter, &jobSheet); err != nil {
+		db.log.Errorf("Jobsheet query error: %s", err)
 		return err
 	}
 	q.Features = jobSheet.Features
+	q.Branch = jobSheet.Branch
+	q.SalesRepID = jobSheet.SalesRepID
 
 	return nil
 }
 
+// findOne decodes the first document matching filter into v, in a span joined to the request span
+func (db *MDB) findOne(col string, filter interface{}, v interface{}, opts ...*options.FindOneOptions) error {
+
+	ctx, span := db.span(col, "findOne")
+	err := db.db.Collection(col).FindOne(ctx, filter, opts...).Decode(v)
+	if err == mongo.ErrNoDocuments {
+		// a missing document is an answer rather than a failed query
+		tracing.End(span, nil)
+		return err
+	}
+	tracing.End(span, err)
+
+	return err
+}
+
+// find decodes all documents matching filter into v, in a span joined to the request span
+func (db *MDB) find(col string, filter interface{}, v interface{}, opts ...*options.FindOptions) (err error) {
+
+	ctx, span := db.span(col, "find")
+	defer func() { tracing.End(span, err) }()
+
+	cur, err := db.db.Collection(col).Find(ctx, filter, opts...)
+	if err != nil {
+		return err
+	}
+
+	return cur.All(ctx, v)
+}
+
+func (db *MDB) span(col, op string) (context.Context, trace.Span) {
+	return tracing.Start(tracing.Context(db.log), "mongo "+col+"."+op,
+		semconv.DBSystemMongoDB,
+		semconv.DBMongoDBCollectionKey.String(col),
+		semconv.DBOperationKey.String(op),
+	)
+}
+
+// since records the duration of a FetchQuote step
+func (db *MDB) since(step string, start time.Time) {
+	metrics.Since(db.metrics, metrics.FetchDuration, start, metrics.Dims{metrics.DimStep: step})
+}
+
+// WithLogger method
+// Returns a handler sharing the connection that logs to l, used to tag log lines with the request
+func (db *MDB) WithLogger(l *log.Entry) model.DBHandler {
+	c := *db
+	c.log = l
+	return &c
+}
+
+// Ping method
+// Checks the connection to the primary, used by the health check
+func (db *MDB) Ping(ctx context.Context) error {
+	return db.client.Ping(ctx, readpref.Primary())
+}
+
 // Close method
 func (db *MDB) Close() {
 	err := db.client.Disconnect(context.Background())
 	if err != nil {
-		log.Fatal(err)
+		db.log.Errorf("Error closing MongoDB connection: %s", err)
+		return
 	}
-	fmt.Println("Connection to MongoDB closed.")
+	db.log.Info("Connection to MongoDB closed")
 }
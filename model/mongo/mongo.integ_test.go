@@ -7,7 +7,9 @@ import (
 	"testing"
 
 	"github.com/pulpfree/univsales-wrksht-pdf/config"
+	"github.com/pulpfree/univsales-wrksht-pdf/metrics"
 	"github.com/pulpfree/univsales-wrksht-pdf/model"
+	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/suite"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -43,9 +45,11 @@ func (suite *IntegSuite) SetupTest() {
 	suite.NoError(err)
 
 	suite.db = &MDB{
-		client: client,
-		dbName: suite.cfg.DBName,
-		db:     client.Database(suite.cfg.DBName),
+		client:  client,
+		dbName:  suite.cfg.DBName,
+		db:      client.Database(suite.cfg.DBName),
+		log:     log.NewEntry(log.StandardLogger()),
+		metrics: metrics.Nop{},
 	}
 	suite.q = &model.Quote{}
 	suite.q.Items = &model.Items{}
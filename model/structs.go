@@ -8,7 +8,9 @@ import (
 )
 
 // Quote struct
+// Branch and SalesRepID are set from the jobsheet, or the customer, for the access checks
 type Quote struct {
+	Branch     string    `bson:"-" json:"-"`
 	CreatedAt  time.Time `bson:"createdAt" json:"createdAt"`
 	Customer   *Customer
 	CustomerID primitive.ObjectID `bson:"customerID" json:"customerID"`
@@ -22,8 +24,9 @@ type Quote struct {
 		TotalCost   float64 `bson:"total"`
 		Outstanding float64 `bson:"outstanding"`
 	} `bson:"quotePrice"`
-	Revision  int       `bson:"version" bson:"version"`
-	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"`
+	Revision   int       `bson:"version" bson:"version"`
+	SalesRepID string    `bson:"-" json:"-"`
+	UpdatedAt  time.Time `bson:"updatedAt" json:"updatedAt"`
 }
 
 // Address struct
@@ -39,8 +42,9 @@ type Address struct {
 
 // Customer struct
 type Customer struct {
-	Email string `bson:"email" json:"email"`
-	Name  struct {
+	Branch string `bson:"branch" json:"-"`
+	Email  string `bson:"email" json:"email"`
+	Name   struct {
 		First  string `bson:"first" json:"first"`
 		Last   string `bson:"last" json:"last"`
 		Spouse string `bson:"spouse" json:"spouse,omitempty"`
@@ -95,10 +99,38 @@ type Items struct {
 	Window []*Window
 }
 
+// JobStatus string
+type JobStatus string
+
+// Job status constants
+const (
+	JobQueued  JobStatus = "queued"
+	JobRunning JobStatus = "running"
+	JobDone    JobStatus = "done"
+	JobFailed  JobStatus = "failed"
+)
+
+// Job struct
+type Job struct {
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+	Error     string             `bson:"error,omitempty" json:"error,omitempty"`
+	Failures  []string           `bson:"failures,omitempty" json:"failures,omitempty"`
+	ID        primitive.ObjectID `bson:"_id" json:"id"`
+	Key       string             `bson:"key,omitempty" json:"key,omitempty"`
+	Kind      string             `bson:"kind" json:"kind"`
+	Payload   string             `bson:"payload" json:"-"`
+	Status    JobStatus          `bson:"status" json:"status"`
+	UpdatedAt time.Time          `bson:"updatedAt" json:"updatedAt"`
+	URL       string             `bson:"-" json:"url,omitempty"`
+	UserID    string             `bson:"userID,omitempty" json:"-"`
+}
+
 // JobSheet struct
 type JobSheet struct {
-	ID       primitive.ObjectID `bson:"_id" json:"id"`
-	Features string             `bson:"features" json:"features,omitempty"`
+	Branch     string             `bson:"branch" json:"branch,omitempty"`
+	ID         primitive.ObjectID `bson:"_id" json:"id"`
+	Features   string             `bson:"features" json:"features,omitempty"`
+	SalesRepID string             `bson:"salesRepID" json:"salesRepID,omitempty"`
 }
 
 // Other struct
@@ -0,0 +1,171 @@
+package notify
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pulpfree/univsales-wrksht-pdf/awsservices"
+	"github.com/pulpfree/univsales-wrksht-pdf/config"
+)
+
+// Event type constants
+const (
+	WorksheetGenerated = "worksheet.generated"
+)
+
+// Source is the EventBridge source of the published events
+const Source = "univsales.wrksht-pdf"
+
+// memorySize is the number of recent events the in-memory publisher keeps
+const memorySize = 100
+
+// Event struct
+type Event struct {
+	Fingerprint string    `json:"fingerprint"`
+	Key         string    `json:"key"`
+	Number      int       `json:"number"`
+	Principal   string    `json:"principal,omitempty"`
+	QuoteID     string    `json:"quoteID"`
+	Revision    int       `json:"revision"`
+	Time        time.Time `json:"time"`
+	Type        string    `json:"type"`
+}
+
+// Publisher interface
+type Publisher interface {
+	Publish(e *Event) error
+}
+
+// New function
+// Returns the publisher for the first configured target of: SNS topic, EventBridge bus, events file.
+// Without any target events are kept in memory
+func New(cfg *config.Config) Publisher {
+	switch {
+	case cfg.EventTopicARN != "":
+		return NewSNS(cfg)
+	case cfg.EventBusName != "":
+		return NewEventBridge(cfg)
+	case cfg.EventsFile != "":
+		return NewFile(cfg.EventsFile)
+	}
+	return NewMemory(memorySize)
+}
+
+// SNS struct
+type SNS struct {
+	cfg *config.Config
+}
+
+// NewSNS function
+func NewSNS(cfg *config.Config) *SNS {
+	return &SNS{cfg: cfg}
+}
+
+// Publish method
+func (p *SNS) Publish(e *Event) error {
+
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	return awsservices.PublishMessage(p.cfg.EventTopicARN, e.Type, string(body), p.cfg)
+}
+
+// EventBridge struct
+type EventBridge struct {
+	cfg *config.Config
+}
+
+// NewEventBridge function
+func NewEventBridge(cfg *config.Config) *EventBridge {
+	return &EventBridge{cfg: cfg}
+}
+
+// Publish method
+func (p *EventBridge) Publish(e *Event) error {
+
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	return awsservices.PutEvent(p.cfg.EventBusName, Source, e.Type, string(body), p.cfg)
+}
+
+// File struct
+// Appends each event as a line of JSON, used when running outside of AWS
+type File struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFile function
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+// Publish method
+func (p *File) Publish(e *Event) error {
+
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	f, err := os.OpenFile(p.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(body, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// Memory struct
+// Keeps the most recent events, used in tests and when no target is configured
+type Memory struct {
+	events []*Event
+	mu     sync.Mutex
+	size   int
+}
+
+// NewMemory function
+func NewMemory(size int) *Memory {
+	return &Memory{size: size}
+}
+
+// Publish method
+func (p *Memory) Publish(e *Event) error {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.events = append(p.events, e)
+	if len(p.events) > p.size {
+		p.events = p.events[len(p.events)-p.size:]
+	}
+
+	return nil
+}
+
+// Events method
+// Returns the published events, oldest first
+func (p *Memory) Events() []*Event {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	events := make([]*Event, len(p.events))
+	copy(events, p.events)
+
+	return events
+}
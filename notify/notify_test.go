@@ -0,0 +1,62 @@
+package notify
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// UnitSuite struct
+type UnitSuite struct {
+	suite.Suite
+}
+
+// TestMemory method
+func (suite *UnitSuite) TestMemory() {
+
+	p := NewMemory(2)
+	for i := 1; i <= 3; i++ {
+		suite.NoError(p.Publish(&Event{Number: i, Type: WorksheetGenerated}))
+	}
+
+	events := p.Events()
+	suite.Len(events, 2)
+	suite.Equal(2, events[0].Number)
+	suite.Equal(3, events[1].Number)
+}
+
+// TestFile method
+func (suite *UnitSuite) TestFile() {
+
+	dir, err := ioutil.TempDir("", "wrksht")
+	suite.NoError(err)
+	defer os.RemoveAll(dir)
+
+	fp := filepath.Join(dir, "events.jsonl")
+	p := NewFile(fp)
+	suite.NoError(p.Publish(&Event{Key: "a.pdf", Type: WorksheetGenerated}))
+	suite.NoError(p.Publish(&Event{Key: "b.pdf", Type: WorksheetGenerated}))
+
+	f, err := os.Open(fp)
+	suite.NoError(err)
+	defer f.Close()
+
+	keys := []string{}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		e := &Event{}
+		suite.NoError(json.Unmarshal(sc.Bytes(), e))
+		keys = append(keys, e.Key)
+	}
+	suite.Equal([]string{"a.pdf", "b.pdf"}, keys)
+}
+
+// TestUnitSuite function
+func TestUnitSuite(t *testing.T) {
+	suite.Run(t, new(UnitSuite))
+}
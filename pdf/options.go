@@ -0,0 +1,190 @@
+package pdf
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pulpfree/univsales-wrksht-pdf/model"
+)
+
+// Paper size constants, as named by gofpdf
+const (
+	PaperA4     = "A4"
+	PaperLegal  = "Legal"
+	PaperLetter = "Letter"
+)
+
+// Orientation constants
+const (
+	OrientationLandscape = "landscape"
+	OrientationPortrait  = "portrait"
+)
+
+// Section constants
+// The quote title is always rendered, financials and notes are parts of it
+const (
+	SectionFeatures   = "features"
+	SectionFinancials = "financials"
+	SectionGroups     = "groups"
+	SectionMisc       = "misc"
+	SectionNotes      = "notes"
+	SectionWindows    = "windows"
+)
+
+// Sort order constants
+const (
+	SortProduct = "product"
+	SortQuote   = "quote"
+	SortRoom    = "room"
+	SortSize    = "size"
+)
+
+// Font scale limits
+const (
+	MaxFontScale = 1.5
+	MinFontScale = 0.75
+)
+
+// Valid option values, in the order they are listed to clients
+var (
+	Orientations = []string{OrientationPortrait, OrientationLandscape}
+	PaperSizes   = []string{PaperLetter, PaperA4, PaperLegal}
+	Sections     = []string{SectionGroups, SectionWindows, SectionMisc, SectionFeatures, SectionFinancials, SectionNotes}
+	SortOrders   = []string{SortQuote, SortRoom, SortProduct, SortSize}
+)
+
+// Options struct
+// Rendering options, the zero value renders the full worksheet on portrait Letter paper
+type Options struct {
+	FontScale   float64  `json:"fontScale,omitempty"`
+	Orientation string   `json:"orientation,omitempty"`
+	PaperSize   string   `json:"paperSize,omitempty"`
+	Sections    []string `json:"sections,omitempty"`
+	Sort        string   `json:"sort,omitempty"`
+}
+
+// Normalize method
+// Returns a copy with the defaults set and the sections in a fixed order, so equal options compare equal.
+// Safe to call on a nil Options
+func (o *Options) Normalize() *Options {
+
+	n := &Options{
+		FontScale:   1,
+		Orientation: OrientationPortrait,
+		PaperSize:   PaperLetter,
+		Sections:    Sections,
+		Sort:        SortQuote,
+	}
+	if o == nil {
+		return n
+	}
+
+	if o.FontScale != 0 {
+		n.FontScale = o.FontScale
+	}
+	if o.Orientation != "" {
+		n.Orientation = o.Orientation
+	}
+	if o.PaperSize != "" {
+		n.PaperSize = o.PaperSize
+	}
+	if o.Sort != "" {
+		n.Sort = o.Sort
+	}
+	if len(o.Sections) > 0 {
+		n.Sections = []string{}
+		for _, s := range Sections {
+			if contains(o.Sections, s) {
+				n.Sections = append(n.Sections, s)
+			}
+		}
+	}
+
+	return n
+}
+
+// ================================ Helper Methods
+
+func (o *Options) has(section string) bool {
+	return contains(o.Sections, section)
+}
+
+func (o *Options) orientation() string {
+	if o.Orientation == OrientationLandscape {
+		return "L"
+	}
+	return "P"
+}
+
+func (o *Options) sortGroups(items []*model.Group) []*model.Group {
+
+	s := append([]*model.Group{}, items...)
+	switch o.Sort {
+	case SortRoom:
+		sort.SliceStable(s, func(i, j int) bool { return rooms(s[i].Rooms) < rooms(s[j].Rooms) })
+	case SortProduct:
+		sort.SliceStable(s, func(i, j int) bool {
+			return specString(s[i].Specs, "groupTypeDescription") < specString(s[j].Specs, "groupTypeDescription")
+		})
+	case SortSize:
+		sort.SliceStable(s, func(i, j int) bool { return area(s[i].Dims) > area(s[j].Dims) })
+	}
+
+	return s
+}
+
+func (o *Options) sortWindows(items []*model.Window) []*model.Window {
+
+	s := append([]*model.Window{}, items...)
+	switch o.Sort {
+	case SortRoom:
+		sort.SliceStable(s, func(i, j int) bool { return rooms(s[i].Rooms) < rooms(s[j].Rooms) })
+	case SortProduct:
+		sort.SliceStable(s, func(i, j int) bool { return s[i].ProductName < s[j].ProductName })
+	case SortSize:
+		sort.SliceStable(s, func(i, j int) bool { return area(s[i].Dims) > area(s[j].Dims) })
+	}
+
+	return s
+}
+
+// sortOthers leaves size order unchanged, misc items have no dimensions
+func (o *Options) sortOthers(items []*model.Other) []*model.Other {
+
+	s := append([]*model.Other{}, items...)
+	switch o.Sort {
+	case SortRoom:
+		sort.SliceStable(s, func(i, j int) bool { return rooms(s[i].Rooms) < rooms(s[j].Rooms) })
+	case SortProduct:
+		sort.SliceStable(s, func(i, j int) bool { return s[i].Description < s[j].Description })
+	}
+
+	return s
+}
+
+// ================================ Helper Functions
+
+func contains(l []string, v string) bool {
+	for _, s := range l {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func rooms(r []string) string {
+	return strings.ToLower(strings.Join(r, ", "))
+}
+
+func specString(specs map[string]interface{}, key string) string {
+	s, _ := specs[key].(string)
+	return s
+}
+
+func area(d *model.Dims) float64 {
+	if d == nil || d.Width == nil || d.Height == nil {
+		return 0
+	}
+	return d.Width.Decimal * d.Height.Decimal
+}
@@ -0,0 +1,73 @@
+package pdf
+
+import (
+	"testing"
+
+	"github.com/pulpfree/univsales-wrksht-pdf/model"
+	"github.com/stretchr/testify/suite"
+)
+
+// OptionsSuite struct
+type OptionsSuite struct {
+	suite.Suite
+}
+
+// TestNormalize method
+func (suite *OptionsSuite) TestNormalize() {
+
+	var o *Options
+	n := o.Normalize()
+	suite.Equal(PaperLetter, n.PaperSize)
+	suite.Equal("P", n.orientation())
+	suite.Equal(1.0, n.FontScale)
+	suite.Equal(Sections, n.Sections)
+
+	n = (&Options{Orientation: OrientationLandscape, Sections: []string{SectionNotes, SectionGroups}}).Normalize()
+	suite.Equal("L", n.orientation())
+	suite.Equal([]string{SectionGroups, SectionNotes}, n.Sections)
+	suite.False(n.has(SectionFinancials))
+
+	suite.Equal(o.Normalize(), (&Options{PaperSize: PaperLetter, Sort: SortQuote}).Normalize())
+}
+
+// TestSort method
+func (suite *OptionsSuite) TestSort() {
+
+	dims := func(w, h float64) *model.Dims {
+		return &model.Dims{Width: &model.Dim{Decimal: w}, Height: &model.Dim{Decimal: h}}
+	}
+	wins := []*model.Window{
+		{Dims: dims(20, 30), ProductName: "Casement", Rooms: []string{"Kitchen"}},
+		{Dims: dims(40, 50), ProductName: "Awning", Rooms: []string{"bedroom"}},
+		{Dims: dims(10, 10), ProductName: "Bay", Rooms: []string{"Den"}},
+	}
+
+	names := func(ws []*model.Window) []string {
+		n := []string{}
+		for _, w := range ws {
+			n = append(n, w.ProductName)
+		}
+		return n
+	}
+
+	tests := []struct {
+		sort string
+		want []string
+	}{
+		{SortQuote, []string{"Casement", "Awning", "Bay"}},
+		{SortProduct, []string{"Awning", "Bay", "Casement"}},
+		{SortRoom, []string{"Awning", "Bay", "Casement"}},
+		{SortSize, []string{"Awning", "Casement", "Bay"}},
+	}
+
+	for _, tt := range tests {
+		o := (&Options{Sort: tt.sort}).Normalize()
+		suite.Equal(tt.want, names(o.sortWindows(wins)), tt.sort)
+	}
+	suite.Equal("Casement", wins[0].ProductName, "the quote items are not reordered")
+}
+
+// TestOptionsSuite function
+func TestOptionsSuite(t *testing.T) {
+	suite.Run(t, new(OptionsSuite))
+}
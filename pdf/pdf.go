@@ -2,13 +2,17 @@ package pdf
 
 import (
 	"bytes"
-	"strconv"
-	"strings"
+	"fmt"
+	"path"
+	"time"
 
 	"github.com/jung-kurt/gofpdf"
-	"github.com/pulpfree/univsales-wrksht-pdf/awsservices"
+	"github.com/pulpfree/univsales-wrksht-pdf/cognito"
 	"github.com/pulpfree/univsales-wrksht-pdf/config"
+	"github.com/pulpfree/univsales-wrksht-pdf/email"
+	"github.com/pulpfree/univsales-wrksht-pdf/metrics"
 	"github.com/pulpfree/univsales-wrksht-pdf/model"
+	log "github.com/sirupsen/logrus"
 )
 
 const (
@@ -18,29 +22,64 @@ const (
 	coAddressPostal   = "L3B 5N5"
 	coAddressProvince = "Ontario"
 	coDomain          = "universalwindows.ca"
+	// versionFormat sorts in creation order, the milliseconds keep quick re-renders apart
+	versionFormat = "20060102T150405.000Z"
 )
 
 // PDF struct
 type PDF struct {
 	Request        *Request
 	cfg            *config.Config
+	created        time.Time
+	log            *log.Entry
+	metrics        metrics.Recorder
+	opts           *Options
 	outputFileName string
 	pdf            *gofpdf.Fpdf
 	q              *model.Quote
 }
 
+// LatestFile is the name of the pointer to the latest worksheet version under a quote's KeyPrefix
+const LatestFile = "latest.json"
+
+// Delivery constants
+const (
+	DeliveryInline = "inline"
+	DeliveryS3     = "s3"
+)
+
 // Request struct
 type Request struct {
-	QuoteID string `json:"quoteID"`
+	Async    bool           `json:"async,omitempty"`
+	Delivery string         `json:"delivery,omitempty"`
+	Email    *email.Options `json:"email,omitempty"`
+	Force    bool           `json:"force,omitempty"`
+	// Identity and Principal are set from the authorizer context, any value in the request body is replaced
+	Identity  *cognito.Identity `json:"identity,omitempty"`
+	Options   *Options          `json:"options,omitempty"`
+	Principal string            `json:"principal,omitempty"`
+	QuoteID   string            `json:"quoteID"`
 }
 
 // New function
+// The output file name is set here so the storage key is known before rendering
 func New(r *Request, q *model.Quote, cfg *config.Config) *PDF {
-	return &PDF{
+	p := &PDF{
 		Request: r,
 		cfg:     cfg,
+		created: time.Now().UTC(),
+		log:     log.NewEntry(log.StandardLogger()),
+		metrics: metrics.Default(),
+		opts:    r.Options.Normalize(),
 		q:       q,
 	}
+	p.setOutputFileName()
+	return p
+}
+
+// Quote method
+func (p *PDF) Quote() *model.Quote {
+	return p.q
 }
 
 // OutputToDisk method
@@ -50,23 +89,65 @@ func (p *PDF) OutputToDisk() (err error) {
 	return err
 }
 
-// SaveToS3 method
-func (p *PDF) SaveToS3() (location string, err error) {
+// Bytes method
+// Returns the rendered document, used when the file is returned directly to the client
+func (p *PDF) Bytes() ([]byte, error) {
 	var buf bytes.Buffer
 	if err := p.pdf.Output(&buf); err != nil {
-		return "", err
+		return nil, err
 	}
-	location, err = awsservices.PutFile(p.outputFileName, &buf, p.cfg)
-	return location, err
+	return buf.Bytes(), nil
 }
 
-// ================================ Helper Methods
+// UseLogger method
+// Sets the logger for the render, used to tag log lines with the request
+func (p *PDF) UseLogger(l *log.Entry) *PDF {
+	p.log = l
+	return p
+}
 
-func (p *PDF) setOutputFileName() {
+// Options method
+// Returns the normalized rendering options
+func (p *PDF) Options() *Options {
+	return p.opts
+}
+
+// OutputFileName method
+// Returns the storage key of the output file
+func (p *PDF) OutputFileName() string {
+	return p.outputFileName
+}
+
+// KeyPrefix function
+// Returns the storage prefix holding every version of a quote's worksheet
+func KeyPrefix(number int) string {
+	return fmt.Sprintf("worksheet/sht-%d/", number)
+}
 
-	var retStr []string
+// LatestKey function
+// Returns the storage key of the pointer to the latest version of a quote's worksheet
+func LatestKey(number int) string {
+	return KeyPrefix(number) + LatestFile
+}
 
-	retStr = []string{"worksheet/sht-", strconv.Itoa(p.q.Number), ".pdf"}
+// FileName method
+// Returns the base name of the output file, without the storage prefix
+func (p *PDF) FileName() string {
+	return path.Base(p.outputFileName)
+}
 
-	p.outputFileName = strings.Join(retStr, "")
+// ================================ Helper Methods
+
+// setOutputFileName sets a versioned key so a new render never overwrites an earlier one
+func (p *PDF) setOutputFileName() {
+	p.outputFileName = fmt.Sprintf("%ssht-%d-r%d-%s.pdf", KeyPrefix(p.q.Number), p.q.Number, p.q.Revision, p.created.Format(versionFormat))
+}
+
+// generated returns the footer stamp naming the requesting user when the request has one
+func (p *PDF) generated() string {
+	date := p.created.Format(dateFormat)
+	if p.Request == nil || p.Request.Identity == nil || p.Request.Identity.Name == "" {
+		return "Generated on " + date
+	}
+	return fmt.Sprintf("Generated by %s on %s", p.Request.Identity.Name, date)
 }
@@ -61,7 +61,7 @@ func (suite *IntegSuite) TestSetFileNameOutput() {
 	}
 	suite.p = New(req, suite.q, suite.cfg)
 	suite.p.setOutputFileName()
-	r, _ := regexp.Compile("^worksheet\\/sht-([0-9]+)\\.pdf?")
+	r, _ := regexp.Compile("^worksheet\\/sht-([0-9]+)\\/sht-([0-9]+)-r([0-9]+)-([0-9T.]+)Z\\.pdf$")
 	suite.True(r.MatchString(suite.p.outputFileName))
 }
 
@@ -0,0 +1,90 @@
+package pdf
+
+import (
+	"fmt"
+
+	"github.com/pulpfree/univsales-wrksht-pdf/model"
+)
+
+// Validate function
+// Returns warnings for quote data that is missing or would not render as expected.
+// Warnings prefixed with "error:" would stop the worksheet from rendering.
+func Validate(q *model.Quote) []string {
+
+	w := []string{}
+	if q.Customer == nil {
+		return append(w, "error: customer is missing")
+	}
+	if q.Customer.Address == nil {
+		w = append(w, "error: customer address is missing")
+	}
+	if q.Customer.PhoneMap["mobile"] == "" && q.Customer.PhoneMap["home"] == "" {
+		w = append(w, "customer has no mobile or home phone")
+	}
+	if q.Customer.Email == "" {
+		w = append(w, "customer has no email")
+	}
+	if q.Items == nil {
+		return append(w, "error: quote items are missing")
+	}
+
+	for i, g := range q.Items.Group {
+		item := fmt.Sprintf("group %d", i+1)
+		w = append(w, validateDims(item, g.Dims)...)
+		w = append(w, validateSpecs(item, g.Specs, "groupTypeDescription", "trim", "options")...)
+		if g.Specs["installType"] == nil {
+			w = append(w, item+" has no install type")
+		}
+		for j, gw := range g.Items {
+			w = append(w, validateDims(fmt.Sprintf("%s window %d", item, j+1), gw.Dims)...)
+			if gw.Product["name"] == nil {
+				w = append(w, fmt.Sprintf("%s window %d has no product name", item, j+1))
+			}
+		}
+	}
+
+	for i, win := range q.Items.Window {
+		item := fmt.Sprintf("window %d", i+1)
+		w = append(w, validateDims(item, win.Dims)...)
+		w = append(w, validateSpecs(item, win.Specs, "options")...)
+		if win.Specs["installType"] == nil {
+			w = append(w, item+" has no install type")
+		}
+		if win.Specs["trim"] == nil {
+			w = append(w, item+" has no trim")
+		}
+		if win.ProductName == "" {
+			w = append(w, item+" has no product name")
+		}
+	}
+
+	for i, o := range q.Items.Other {
+		if o.Description == "" {
+			w = append(w, fmt.Sprintf("misc item %d has no description", i+1))
+		}
+	}
+
+	if q.Features == "" {
+		w = append(w, "jobsheet has no features")
+	}
+
+	return w
+}
+
+func validateDims(item string, d *model.Dims) []string {
+	if d == nil || d.Width == nil || d.Height == nil {
+		return []string{fmt.Sprintf("error: %s dimensions are missing", item)}
+	}
+	return nil
+}
+
+// validateSpecs checks for specs that must be strings, as the sections assert their type
+func validateSpecs(item string, specs map[string]interface{}, keys ...string) []string {
+	w := []string{}
+	for _, k := range keys {
+		if _, ok := specs[k].(string); !ok {
+			w = append(w, fmt.Sprintf("error: %s spec %s is missing", item, k))
+		}
+	}
+	return w
+}
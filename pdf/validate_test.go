@@ -0,0 +1,60 @@
+package pdf
+
+import (
+	"testing"
+
+	"github.com/pulpfree/univsales-wrksht-pdf/model"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ValidateSuite struct
+type ValidateSuite struct {
+	suite.Suite
+	q *model.Quote
+}
+
+// SetupTest method
+func (suite *ValidateSuite) SetupTest() {
+	dims := &model.Dims{Height: &model.Dim{Inch: 40}, Width: &model.Dim{Inch: 30}}
+	suite.q = &model.Quote{
+		Customer: &model.Customer{
+			Address:  &model.Address{City: "Welland"},
+			Email:    "test@example.com",
+			PhoneMap: map[string]string{"mobile": "905-555-1234"},
+		},
+		Features: "Caulking",
+		Items: &model.Items{
+			Window: []*model.Window{{
+				Dims:        dims,
+				ProductName: "Casement",
+				Specs:       bson.M{"installType": "Retrofit", "options": "Low E", "trim": "Brickmould"},
+			}},
+		},
+	}
+}
+
+func (suite *ValidateSuite) TestValidQuote() {
+	suite.Empty(Validate(suite.q))
+}
+
+func (suite *ValidateSuite) TestMissingCustomer() {
+	suite.q.Customer = nil
+	suite.Equal([]string{"error: customer is missing"}, Validate(suite.q))
+}
+
+func (suite *ValidateSuite) TestWindowWarnings() {
+	suite.q.Items.Window[0].Dims = nil
+	delete(suite.q.Items.Window[0].Specs, "options")
+	delete(suite.q.Items.Window[0].Specs, "trim")
+
+	w := Validate(suite.q)
+	suite.Contains(w, "error: window 1 dimensions are missing")
+	suite.Contains(w, "error: window 1 spec options is missing")
+	suite.Contains(w, "window 1 has no trim")
+}
+
+// TestValidateSuite function
+func TestValidateSuite(t *testing.T) {
+	suite.Run(t, new(ValidateSuite))
+}
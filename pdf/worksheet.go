@@ -1,13 +1,20 @@
 package pdf
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dustin/go-humanize"
 	"github.com/jung-kurt/gofpdf"
+	"github.com/pulpfree/univsales-wrksht-pdf/config"
+	"github.com/pulpfree/univsales-wrksht-pdf/metrics"
+	"github.com/pulpfree/univsales-wrksht-pdf/tracing"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Various constants
@@ -28,26 +35,89 @@ func (p *PDF) WorkSheet() (err error) {
 	p.setOutputFileName()
 	titleStr := "Worksheet " + strconv.Itoa(p.q.Number) + " PDF"
 
-	p.pdf = gofpdf.New("P", "mm", "Letter", "")
-	pdf := p.pdf
-	pdf.SetTitle(titleStr, false)
-	pdf.SetAuthor(p.cfg.DocAuthor, false)
+	p.log.WithFields(log.Fields{
+		"number":   p.q.Number,
+		"paper":    p.opts.PaperSize,
+		"revision": p.q.Revision,
+		"sections": p.opts.Sections,
+	}).Debug("Rendering worksheet")
+
+	ctx, span := tracing.Start(tracing.Context(p.log), "render worksheet", attribute.Int("quote.number", p.q.Number))
+	defer span.End()
+
+	start := time.Now()
+	p.pdf = newDocument(titleStr, p.cfg, p.opts, p.generated())
+	p.pdf.AddPage()
+	p.sections(ctx)
+
+	metrics.Since(p.metrics, metrics.RenderDuration, start, nil)
+	p.metrics.Record(metrics.RenderPages, float64(p.pdf.PageCount()), metrics.Count, nil)
+
+	return err
+}
+
+// Merge function
+// Renders the worksheets into a single document, each quote starting on a new page.
+// The paper size and orientation of opts apply to every page
+func Merge(ps []*PDF, cfg *config.Config, opts *Options) *PDF {
+
+	m := &PDF{cfg: cfg, created: time.Now().UTC(), log: log.NewEntry(log.StandardLogger()), metrics: metrics.Default(), opts: opts.Normalize()}
+	// the worksheets of a batch share the requesting user
+	if len(ps) > 0 {
+		m.Request = ps[0].Request
+	}
+	m.pdf = newDocument("Worksheets PDF", cfg, m.opts, m.generated())
+	for _, p := range ps {
+		p.setOutputFileName()
+		p.pdf = m.pdf
+		p.pdf.AddPage()
+		p.sections(tracing.Context(p.log))
+	}
+
+	return m
+}
+
+// newDocument sets up the document, each page footer has the page number and the generated by stamp
+func newDocument(title string, cfg *config.Config, opts *Options, stamp string) *gofpdf.Fpdf {
+
+	pdf := gofpdf.New(opts.orientation(), "mm", opts.PaperSize, "")
+	pdf.SetTitle(title, false)
+	pdf.SetAuthor(cfg.DocAuthor, false)
 
 	pdf.SetFooterFunc(func() {
 		pdf.SetY(-15)
 		pdf.SetFont("Arial", "I", 9)
+		left, _, _, _ := pdf.GetMargins()
+		pdf.CellFormat(0, 10, stamp, "", 0, "R", false, 0, "")
+		pdf.SetX(left)
 		pdf.CellFormat(0, 10, fmt.Sprintf("Page %d of {nb}", pdf.PageNo()), "", 0, "C", false, 0, "")
 	})
 	pdf.AliasNbPages("")
 
-	pdf.AddPage()
-	p.quoteTitle()
-	p.groupList()
-	p.windowList()
-	p.otherList()
-	p.featureList()
+	return pdf
+}
 
-	return err
+func (p *PDF) sections(ctx context.Context) {
+	p.section(ctx, "title", p.quoteTitle)
+	if p.opts.has(SectionGroups) {
+		p.section(ctx, SectionGroups, p.groupList)
+	}
+	if p.opts.has(SectionWindows) {
+		p.section(ctx, SectionWindows, p.windowList)
+	}
+	if p.opts.has(SectionMisc) {
+		p.section(ctx, SectionMisc, p.otherList)
+	}
+	if p.opts.has(SectionFeatures) {
+		p.section(ctx, SectionFeatures, p.featureList)
+	}
+}
+
+// section renders one worksheet section in its own span, the span still ends when the section panics
+func (p *PDF) section(ctx context.Context, name string, render func()) {
+	_, span := tracing.Start(ctx, "render "+name)
+	defer span.End()
+	render()
 }
 
 func (p *PDF) quoteTitle() {
@@ -55,31 +125,30 @@ func (p *PDF) quoteTitle() {
 	pdf := p.pdf
 	q := p.q
 
-	fmt.Printf("q.Fees %+v\n", q.Fees)
-
-	var (
-		rsp     *http.Response
-		tp      string
-		imgInfo gofpdf.ImageOptions
-	)
-
-	rsp, err := http.Get(p.cfg.LogoURI)
-	if err == nil {
-		tp = pdf.ImageTypeFromMime(rsp.Header["Content-Type"][0])
-		imgInfo = gofpdf.ImageOptions{ImageType: tp}
-		pdf.RegisterImageReader(p.cfg.LogoURI, tp, rsp.Body)
-	} else {
-		pdf.SetError(err)
+	var imgInfo gofpdf.ImageOptions
+
+	// the worksheets of a merged document share the logo, so it is fetched once per document
+	if pdf.GetImageInfo(p.cfg.LogoURI) == nil {
+		rsp, err := http.Get(p.cfg.LogoURI)
+		if err == nil {
+			defer rsp.Body.Close()
+			tp := pdf.ImageTypeFromMime(rsp.Header["Content-Type"][0])
+			imgInfo = gofpdf.ImageOptions{ImageType: tp}
+			pdf.RegisterImageReader(p.cfg.LogoURI, tp, rsp.Body)
+		} else {
+			p.log.Errorf("Error fetching logo: %s", err)
+			pdf.SetError(err)
+		}
 	}
 	custName := fmt.Sprintf("%s %s", q.Customer.Name.First, q.Customer.Name.Last)
 	address2 := fmt.Sprintf("%s, %s. %s", q.Customer.Address.City, q.Customer.Address.Province, q.Customer.Address.PostalCode)
 	quoteNo := fmt.Sprintf("%d", q.Number)
 
 	pdf.SetTextColor(0, 0, 0)
-	pdf.SetFont("Arial", "B", 14)
+	p.setFont("B", 14)
 	pdf.CellFormat(0, 6, "Worksheet", "", 2, "", false, 0, "")
 
-	pdf.SetFont("Arial", "", 12)
+	p.setFont("", 12)
 	pdf.CellFormat(0, 5.5, custName, "", 2, "", false, 0, "")
 	pdf.CellFormat(0, 5.5, q.Customer.Address.Street1, "", 2, "", false, 0, "")
 	pdf.CellFormat(0, 5.5, address2, "", 2, "", false, 0, "")
@@ -90,41 +159,48 @@ func (p *PDF) quoteTitle() {
 		pdf.CellFormat(0, 5.5, fmt.Sprintf("Home %s", v), "", 2, "", false, 0, "")
 	}
 	pdf.SetTextColor(0, 0, 200)
-	pdf.SetFont("Arial", "U", 12)
+	p.setFont("U", 12)
 	if q.Customer.Email != "" {
 		pdf.CellFormat(0, 5.5, q.Customer.Email, "", 2, "", false, 0, fmt.Sprintf("mailto:%s", q.Customer.Email))
 	}
 
-	pdf.ImageOptions(p.cfg.LogoURI, 80, 10, 45, 0, false, imgInfo, 0, fmt.Sprintf("https://%s", coDomain))
+	// the logo is centred and the invoice details right aligned, whatever the page width
+	pageW, _ := pdf.GetPageSize()
+	rightX := pageW - 66
+	pdf.ImageOptions(p.cfg.LogoURI, pageW/2-28, 10, 45, 0, false, imgInfo, 0, fmt.Sprintf("https://%s", coDomain))
 
-	pdf.MoveTo(150, 10)
+	pdf.MoveTo(rightX, 10)
 	pdf.SetTextColor(0, 0, 0)
-	pdf.SetFont("Arial", "B", 12)
+	p.setFont("B", 12)
 	pdf.CellFormat(25, 6, "Invoice No", "", 0, "", false, 0, "")
-	pdf.SetFont("Arial", "", 12)
+	p.setFont("", 12)
 	pdf.CellFormat(0, 6, quoteNo, "", 1, "", false, 0, "")
-	pdf.MoveTo(150, 16)
-	pdf.SetFont("Arial", "", 10)
-	pdf.CellFormat(32, 5, "Total Cost", "", 0, "", false, 0, "")
-	pdf.CellFormat(10, 5, formatMoney(q.Fees.TotalCost, "$"), "", 2, "", false, 0, "")
-	pdf.MoveTo(150, 22)
-	pdf.CellFormat(32, 5, "Total Outstanding", "", 0, "", false, 0, "")
-	pdf.CellFormat(10, 5, formatMoney(q.Fees.Outstanding, "$"), "", 2, "", false, 0, "")
+	if p.opts.has(SectionFinancials) {
+		pdf.MoveTo(rightX, 16)
+		p.setFont("", 10)
+		pdf.CellFormat(32, 5, "Total Cost", "", 0, "", false, 0, "")
+		pdf.CellFormat(10, 5, formatMoney(q.Fees.TotalCost, "$"), "", 2, "", false, 0, "")
+		pdf.MoveTo(rightX, 22)
+		pdf.CellFormat(32, 5, "Total Outstanding", "", 0, "", false, 0, "")
+		pdf.CellFormat(10, 5, formatMoney(q.Fees.Outstanding, "$"), "", 2, "", false, 0, "")
+	}
 
 	// pdf.MoveTo(160, 30)
-	// pdf.SetFont("Arial", "", 10)
+	// p.setFont("", 10)
 	// pdf.CellFormat(0, 5, coAddressStreet, "", 2, "", false, 0, "")
 	// pdf.CellFormat(0, 5, fmt.Sprintf("%s, %s %s", coAddressCity, coAddressProvince, coAddressPostal), "", 2, "", false, 0, "")
 	// pdf.SetTextColor(0, 0, 200)
-	// pdf.SetFont("Arial", "U", 10)
+	// p.setFont("U", 10)
 	// pdf.CellFormat(0, 5, coDomain, "", 2, "", false, 0, fmt.Sprintf("https://%s", coDomain))
 
 	pdf.MoveTo(10, 50)
-	pdf.SetFont("Arial", "", 9)
 	pdf.SetTextColor(0, 0, 0)
-	pdf.CellFormat(10, 5, "Notes:", "", 0, "", false, 0, "")
-	pdf.SetFont("Arial", "I", 9)
-	pdf.CellFormat(0, 5, q.Customer.Notes, "", 2, "", false, 0, "")
+	if p.opts.has(SectionNotes) {
+		p.setFont("", 9)
+		pdf.CellFormat(10, 5, "Notes:", "", 0, "", false, 0, "")
+		p.setFont("I", 9)
+		pdf.CellFormat(0, 5, q.Customer.Notes, "", 2, "", false, 0, "")
+	}
 
 	pdf.Ln(4)
 }
@@ -141,12 +217,12 @@ func (p *PDF) groupList() {
 	pdf.SetFillColor(100, 100, 100)
 	pdf.SetDrawColor(100, 100, 100)
 	pdf.SetTextColor(0, 0, 0)
-	pdf.SetFont("Arial", "B", 12)
+	p.setFont("B", 12)
 	pdf.CellFormat(0, 6, "Groups", "B", 0, "", false, 0, "")
 	pdf.Ln(midBr)
 
 	ctr := 1
-	for _, g := range q.Items.Group {
+	for _, g := range p.opts.sortGroups(q.Items.Group) {
 		openWidth := fmt.Sprintf("%d %s x %d %s", g.Dims.Width.Inch, g.Dims.Width.Fraction, g.Dims.Height.Inch, g.Dims.Height.Fraction)
 
 		installType := "none"
@@ -154,18 +230,18 @@ func (p *PDF) groupList() {
 			installType = g.Specs["installType"].(string)
 		}
 
-		pdf.SetFont("Arial", "B", pfSize)
+		p.setFont("B", pfSize)
 		pdf.CellFormat(0, 6, fmt.Sprintf("%d%s", ctr, ")"), "", 1, "", false, 0, "")
 
 		pdf.SetDrawColor(200, 200, 200)
-		pdf.SetFont("Arial", "", hdrSize)
+		p.setFont("", hdrSize)
 		pdf.MoveTo(pdf.GetX()+4, pdf.GetY())
 		pdf.CellFormat(7, 5, "Qty", "", 0, "", false, 0, "")
 		pdf.CellFormat(26, 5, "Rooms", "", 0, "", false, 0, "")
 		pdf.CellFormat(35, 5, "Opening Width", "", 0, "", false, 0, "")
 		pdf.CellFormat(45, 5, "Type", "", 1, "", false, 0, "")
 
-		pdf.SetFont("Arial", "", pfSize)
+		p.setFont("", pfSize)
 		pdf.MoveTo(pdf.GetX()+4, pdf.GetY())
 		pdf.CellFormat(7, 6, fmt.Sprintf("%d", g.Qty), "TB", 0, "", false, 0, "")
 		pdf.CellFormat(26, 6, fmt.Sprintf("%s", strings.Join(g.Rooms, ", ")), "TB", 0, "", false, 0, "")
@@ -173,10 +249,10 @@ func (p *PDF) groupList() {
 		pdf.CellFormat(45, 6, g.Specs["groupTypeDescription"].(string), "TB", 1, "", false, 0, "")
 
 		pdf.MoveTo(pdf.GetX()+4, pdf.GetY()+2)
-		pdf.SetFont("Arial", "", hdrSize)
+		p.setFont("", hdrSize)
 		pdf.CellFormat(5, 6, "Windows", "", 1, "", false, 0, "")
 
-		pdf.SetFont("Arial", "", pfSize)
+		p.setFont("", pfSize)
 		for _, item := range g.Items {
 			winSize := fmt.Sprintf("%d %s x %d %s", item.Dims.Width.Inch, item.Dims.Width.Fraction, item.Dims.Height.Inch, item.Dims.Height.Fraction)
 
@@ -188,10 +264,10 @@ func (p *PDF) groupList() {
 
 		// start specs
 		pdf.MoveTo(pdf.GetX()+4, pdf.GetY()+2)
-		pdf.SetFont("Arial", "", hdrSize)
+		p.setFont("", hdrSize)
 		pdf.CellFormat(5, 6, "Specifications", "", 1, "", false, 0, "")
 
-		pdf.SetFont("Arial", "", pfSize)
+		p.setFont("", pfSize)
 		pdf.MoveTo(pdf.GetX()+8, pdf.GetY())
 		pdf.CellFormat(25, 6, "Install Type", "", 0, "", false, 0, "")
 		pdf.CellFormat(60, 6, installType, "", 1, "", false, 0, "")
@@ -222,12 +298,12 @@ func (p *PDF) windowList() {
 	pdf.SetFillColor(100, 100, 100)
 	pdf.SetDrawColor(100, 100, 100)
 	pdf.SetTextColor(0, 0, 0)
-	pdf.SetFont("Arial", "B", 12)
+	p.setFont("B", 12)
 	pdf.CellFormat(0, 6, "Windows", "B", 0, "", false, 0, "")
 	pdf.Ln(midBr)
 
 	ctr := 1
-	for _, g := range q.Items.Window {
+	for _, g := range p.opts.sortWindows(q.Items.Window) {
 
 		windowSize := fmt.Sprintf("%d %s x %d %s", g.Dims.Width.Inch, g.Dims.Width.Fraction, g.Dims.Height.Inch, g.Dims.Height.Fraction)
 		installType := "none"
@@ -239,18 +315,18 @@ func (p *PDF) windowList() {
 			trim = setNewLines(g.Specs["trim"])
 		}
 
-		pdf.SetFont("Arial", "B", pfSize)
+		p.setFont("B", pfSize)
 		pdf.CellFormat(0, 6, fmt.Sprintf("%d%s", ctr, ")"), "", 1, "", false, 0, "")
 
 		pdf.SetDrawColor(200, 200, 200)
-		pdf.SetFont("Arial", "", hdrSize)
+		p.setFont("", hdrSize)
 		pdf.MoveTo(pdf.GetX()+4, pdf.GetY())
 		pdf.CellFormat(7, 5, "Qty", "", 0, "", false, 0, "")
 		pdf.CellFormat(26, 5, "Rooms", "", 0, "", false, 0, "")
 		pdf.CellFormat(35, 5, "Size", "", 0, "", false, 0, "")
 		pdf.CellFormat(45, 5, "Type", "", 1, "", false, 0, "")
 
-		pdf.SetFont("Arial", "", pfSize)
+		p.setFont("", pfSize)
 		pdf.MoveTo(pdf.GetX()+4, pdf.GetY())
 		pdf.CellFormat(7, 6, fmt.Sprintf("%d", g.Qty), "TB", 0, "", false, 0, "")
 		pdf.CellFormat(26, 6, fmt.Sprintf("%s", strings.Join(g.Rooms, ", ")), "TB", 0, "", false, 0, "")
@@ -258,11 +334,11 @@ func (p *PDF) windowList() {
 		pdf.CellFormat(60, 6, g.ProductName, "TB", 1, "", false, 0, "")
 
 		pdf.MoveTo(pdf.GetX()+4, pdf.GetY()+2)
-		pdf.SetFont("Arial", "", hdrSize)
+		p.setFont("", hdrSize)
 		pdf.CellFormat(5, 6, "Specifications", "", 1, "", false, 0, "")
 
 		pdf.MoveTo(pdf.GetX()+8, pdf.GetY())
-		pdf.SetFont("Arial", "", pfSize)
+		p.setFont("", pfSize)
 		pdf.CellFormat(25, 6, "Install Type", "", 0, "", false, 0, "")
 		pdf.CellFormat(95, 6, installType, "", 1, "", false, 0, "")
 
@@ -291,34 +367,34 @@ func (p *PDF) otherList() {
 	pdf.SetFillColor(100, 100, 100)
 	pdf.SetDrawColor(100, 100, 100)
 	pdf.SetTextColor(0, 0, 0)
-	pdf.SetFont("Arial", "B", 12)
+	p.setFont("B", 12)
 	pdf.CellFormat(0, 6, "Misc Items", "B", 0, "", false, 0, "")
 	pdf.Ln(midBr)
 
 	ctr := 1
-	for _, g := range q.Items.Other {
-		pdf.SetFont("Arial", "B", pfSize)
+	for _, g := range p.opts.sortOthers(q.Items.Other) {
+		p.setFont("B", pfSize)
 		pdf.CellFormat(0, 6, fmt.Sprintf("%d%s", ctr, ")"), "", 1, "", false, 0, "")
 
 		pdf.SetDrawColor(200, 200, 200)
-		pdf.SetFont("Arial", "", hdrSize)
+		p.setFont("", hdrSize)
 		pdf.MoveTo(pdf.GetX()+4, pdf.GetY())
 		pdf.CellFormat(7, 5, "Qty", "", 0, "", false, 0, "")
 		pdf.CellFormat(26, 5, "Rooms", "", 0, "", false, 0, "")
 		pdf.CellFormat(35, 5, "Description", "", 1, "", false, 0, "")
 
-		pdf.SetFont("Arial", "", pfSize)
+		p.setFont("", pfSize)
 		pdf.MoveTo(pdf.GetX()+4, pdf.GetY())
 		pdf.CellFormat(7, 6, fmt.Sprintf("%d", g.Qty), "TB", 0, "", false, 0, "")
 		pdf.CellFormat(26, 6, fmt.Sprintf("%s", strings.Join(g.Rooms, ", ")), "TB", 0, "", false, 0, "")
 		pdf.CellFormat(60, 6, g.Description, "TB", 1, "", false, 0, "")
 
 		pdf.MoveTo(pdf.GetX()+4, pdf.GetY()+2)
-		pdf.SetFont("Arial", "", hdrSize)
+		p.setFont("", hdrSize)
 		pdf.CellFormat(5, 6, "Specifications", "", 1, "", false, 0, "")
 
 		pdf.MoveTo(pdf.GetX()+8, pdf.GetY())
-		pdf.SetFont("Arial", "", pfSize)
+		p.setFont("", pfSize)
 		pdf.CellFormat(30, 6, "Options", "", 0, "", false, 0, "")
 		// pdf.CellFormat(95, 6, replaceNLDash(g.Specs.Options), "B", 1, "", false, 0, "")
 		pdf.MultiCell(70, 4.5, setNewLines(g.Specs.Options), "", "LB", false)
@@ -339,16 +415,21 @@ func (p *PDF) featureList() {
 	pdf.SetFillColor(100, 100, 100)
 	pdf.SetDrawColor(100, 100, 100)
 	pdf.SetTextColor(0, 0, 0)
-	pdf.SetFont("Arial", "B", 12)
+	p.setFont("B", 12)
 	pdf.CellFormat(0, 6, "Job Features", "B", 0, "", false, 0, "")
 	pdf.Ln(midBr)
 
-	pdf.SetFont("Arial", "", pfSize)
+	p.setFont("", pfSize)
 	pdf.MultiCell(70, 4.5, setNewLines(q.Features), "", "L", false)
 }
 
 // ================================ Helper Methods
 
+// setFont sets an Arial font, scaled by the font scale option
+func (p *PDF) setFont(style string, size float64) {
+	p.pdf.SetFont("Arial", style, size*p.opts.FontScale)
+}
+
 func setNewLines(name interface{}) string {
 
 	str := name.(string)
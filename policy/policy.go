@@ -0,0 +1,114 @@
+// Package policy parses the group policy tables of the config, which list the api routes each group may call.
+// The authorizer turns a table into the IAM policy api gateway enforces, and the local server checks it directly
+package policy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// All matches every verb in a rule
+const All = "*"
+
+// resourcePattern matches the resource paths allowed in a method ARN, * matches any characters
+var resourcePattern = regexp.MustCompile(`^/[/.a-zA-Z0-9_*-]*$`)
+
+// verbs are the methods a rule may name, as in a method ARN
+var verbs = []string{"GET", "POST", "PUT", "DELETE", "PATCH", "HEAD", "OPTIONS", All}
+
+// Rule struct
+// A verb and resource path a group may invoke, the path may hold * wildcards
+type Rule struct {
+	Verb     string
+	Resource string
+}
+
+// Table type
+// Maps Cognito group, or service client, names to the rules of the group
+type Table map[string][]Rule
+
+// NewTable function
+// Parses the config policy table, where each rule has the form "VERB /path"
+func NewTable(policies map[string][]string) (Table, error) {
+
+	t := Table{}
+	for group, rules := range policies {
+		for _, s := range rules {
+			r, err := parseRule(s)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid rule for group %s: %s", group, err)
+			}
+			t[group] = append(t[group], r)
+		}
+	}
+
+	return t, nil
+}
+
+// Rules method
+// Returns the combined rules of the groups, groups missing from the table add nothing
+func (t Table) Rules(groups []string) []Rule {
+
+	var rules []Rule
+	seen := map[Rule]bool{}
+	for _, g := range groups {
+		for _, r := range t[g] {
+			if !seen[r] {
+				seen[r] = true
+				rules = append(rules, r)
+			}
+		}
+	}
+
+	return rules
+}
+
+// Allows method
+// Reports whether any rule of the groups matches the request method and path
+func (t Table) Allows(groups []string, method, path string) bool {
+	for _, r := range t.Rules(groups) {
+		if r.Matches(method, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches method
+// Matches as api gateway does a method ARN, where * in the resource matches any characters, slashes included
+func (r Rule) Matches(method, path string) bool {
+
+	if r.Verb != All && r.Verb != strings.ToUpper(method) {
+		return false
+	}
+
+	parts := strings.Split(r.Resource, "*")
+	for i, p := range parts {
+		parts[i] = regexp.QuoteMeta(p)
+	}
+
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$").MatchString(path)
+}
+
+// ================================ Helper Functions
+
+func parseRule(s string) (Rule, error) {
+
+	f := strings.Fields(s)
+	if len(f) != 2 || !strings.HasPrefix(f[1], "/") {
+		return Rule{}, fmt.Errorf("expected \"VERB /path\", got %q", s)
+	}
+	if !resourcePattern.MatchString(f[1]) {
+		return Rule{}, fmt.Errorf("invalid resource path %q", f[1])
+	}
+
+	verb := strings.ToUpper(f[0])
+	for _, v := range verbs {
+		if v == verb {
+			return Rule{Verb: verb, Resource: f[1]}, nil
+		}
+	}
+
+	return Rule{}, fmt.Errorf("unknown verb %q", f[0])
+}
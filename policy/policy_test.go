@@ -0,0 +1,72 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// PolicySuite struct
+type PolicySuite struct {
+	suite.Suite
+	t Table
+}
+
+// SetupTest method
+func (suite *PolicySuite) SetupTest() {
+	var err error
+	suite.t, err = NewTable(map[string][]string{
+		"admins":     {"* /*"},
+		"installers": {"GET /worksheets/*"},
+		"sales":      {"POST /", "get /jobs/*"},
+	})
+	suite.NoError(err)
+}
+
+// TestNewTable method
+func (suite *PolicySuite) TestNewTable() {
+
+	suite.Equal([]Rule{{Verb: "POST", Resource: "/"}, {Verb: "GET", Resource: "/jobs/*"}}, suite.t["sales"])
+
+	for _, bad := range []string{"GET", "FETCH /jobs/*", "GET jobs", "GET / extra", "GET /jobs/{id}", "GET /jobs?all"} {
+		_, err := NewTable(map[string][]string{"sales": {bad}})
+		suite.Error(err, bad)
+	}
+}
+
+// TestRules method
+func (suite *PolicySuite) TestRules() {
+	suite.Equal([]Rule{{"POST", "/"}, {"GET", "/jobs/*"}, {"GET", "/worksheets/*"}}, suite.t.Rules([]string{"sales", "installers", "sales"}))
+	suite.Empty(suite.t.Rules([]string{"contractors"}))
+}
+
+// TestAllows method
+func (suite *PolicySuite) TestAllows() {
+
+	tests := []struct {
+		groups  []string
+		method  string
+		path    string
+		allowed bool
+	}{
+		{[]string{"installers"}, "GET", "/worksheets/1042", true},
+		{[]string{"installers"}, "POST", "/worksheets/1042/prune", false},
+		{[]string{"installers"}, "POST", "/", false},
+		{[]string{"sales"}, "POST", "/", true},
+		{[]string{"sales"}, "POST", "/batch", false},
+		{[]string{"sales"}, "get", "/jobs/5f7e", true},
+		{[]string{"sales", "installers"}, "GET", "/worksheets/1042", true},
+		{[]string{"admins"}, "POST", "/worksheets/1042/prune", true},
+		{[]string{"contractors"}, "GET", "/worksheets/1042", false},
+		{nil, "GET", "/worksheets/1042", false},
+	}
+
+	for _, tt := range tests {
+		suite.Equal(tt.allowed, suite.t.Allows(tt.groups, tt.method, tt.path), "%v %s %s", tt.groups, tt.method, tt.path)
+	}
+}
+
+// TestPolicySuite function
+func TestPolicySuite(t *testing.T) {
+	suite.Run(t, new(PolicySuite))
+}
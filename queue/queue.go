@@ -0,0 +1,77 @@
+package queue
+
+import (
+	"errors"
+
+	"github.com/pulpfree/univsales-wrksht-pdf/awsservices"
+	"github.com/pulpfree/univsales-wrksht-pdf/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// localQueueSize is the number of jobs the in-process queue holds before rejecting new ones
+const localQueueSize = 100
+
+// Queue interface
+type Queue interface {
+	Send(jobID string) error
+}
+
+// ConsumeFunc type
+// Processes a single job taken off the queue
+type ConsumeFunc func(jobID string) error
+
+// New function
+// Returns an SQS queue when a queue url is configured, otherwise an in-process queue feeding consume
+func New(cfg *config.Config, consume ConsumeFunc) Queue {
+	if cfg.JobQueueURL != "" {
+		return NewSQS(cfg)
+	}
+	return NewLocal(localQueueSize, consume)
+}
+
+// SQS struct
+type SQS struct {
+	cfg *config.Config
+}
+
+// NewSQS function
+func NewSQS(cfg *config.Config) *SQS {
+	return &SQS{cfg: cfg}
+}
+
+// Send method
+func (q *SQS) Send(jobID string) error {
+	return awsservices.SendMessage(q.cfg.JobQueueURL, jobID, q.cfg)
+}
+
+// Local struct
+// In-process queue used when running outside of AWS
+type Local struct {
+	jobs chan string
+}
+
+// NewLocal function
+// Starts a single worker goroutine that passes each job id to consume
+func NewLocal(size int, consume ConsumeFunc) *Local {
+
+	l := &Local{jobs: make(chan string, size)}
+	go func() {
+		for id := range l.jobs {
+			if err := consume(id); err != nil {
+				log.Errorf("Error processing job %s: %s", id, err)
+			}
+		}
+	}()
+
+	return l
+}
+
+// Send method
+func (l *Local) Send(jobID string) error {
+	select {
+	case l.jobs <- jobID:
+		return nil
+	default:
+		return errors.New("Local job queue is full")
+	}
+}
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pulpfree/univsales-wrksht-pdf/cognito"
+	"github.com/pulpfree/univsales-wrksht-pdf/logger"
+	"github.com/pulpfree/univsales-wrksht-pdf/model"
+	"github.com/pulpfree/univsales-wrksht-pdf/pdf"
+	"github.com/pulpfree/univsales-wrksht-pdf/storage"
+)
+
+// Batch format constants
+const (
+	BatchMerged = "merged"
+	BatchZip    = "zip"
+)
+
+const batchPrefix = "worksheet/batch/"
+
+// MaxBatchQuotes is the largest number of quotes rendered in one batch, whether listed or selected by date range
+const MaxBatchQuotes = 100
+
+// BatchRequest struct
+// Quotes are selected either by QuoteIDs or by the jobsheet DateRange
+type BatchRequest struct {
+	Async     bool       `json:"async,omitempty"`
+	DateRange *DateRange `json:"dateRange,omitempty"`
+	Format    string     `json:"format"`
+	// Identity is set from the authorizer context, any value in the request body is replaced
+	Identity *cognito.Identity `json:"identity,omitempty"`
+	Options  *pdf.Options      `json:"options,omitempty"`
+	QuoteIDs []string          `json:"quoteIDs,omitempty"`
+}
+
+// DateRange struct
+type DateRange struct {
+	End   time.Time `json:"end"`
+	Start time.Time `json:"start"`
+}
+
+// BatchResult struct
+type BatchResult struct {
+	Failed    int               `json:"failed"`
+	File      *storage.FileInfo `json:"file,omitempty"`
+	Quotes    []*BatchItem      `json:"quotes"`
+	Succeeded int               `json:"succeeded"`
+}
+
+// BatchItem struct
+type BatchItem struct {
+	Error   string `json:"error,omitempty"`
+	Number  int    `json:"number,omitempty"`
+	QuoteID string `json:"quoteID"`
+	Success bool   `json:"success"`
+}
+
+type rendered struct {
+	body []byte
+	p    *pdf.PDF
+}
+
+// Batch method
+// Renders each quote with bounded concurrency and uploads a zip or a merged pdf of the successful ones
+func (s *Service) Batch(r *BatchRequest) (*BatchResult, error) {
+
+	if r.Format == "" {
+		r.Format = BatchZip
+	}
+	if r.Format != BatchZip && r.Format != BatchMerged {
+		return nil, &Error{Op: OpBatch, Err: fmt.Errorf("Invalid batch format: %s", r.Format)}
+	}
+
+	quoteIDs, err := s.batchQuoteIDs(r)
+	if err != nil {
+		return nil, &Error{Op: OpFetch, Err: err}
+	}
+	if len(quoteIDs) == 0 {
+		return nil, &Error{Op: OpFetch, Err: fmt.Errorf("%w: no quotes found for batch", model.ErrQuoteNotFound)}
+	}
+	if len(quoteIDs) > MaxBatchQuotes {
+		return nil, &Error{Op: OpBatch, Err: fmt.Errorf("Batch of %d quotes is over the limit of %d", len(quoteIDs), MaxBatchQuotes)}
+	}
+
+	items := make([]*BatchItem, len(quoteIDs))
+	docs := make([]*rendered, len(quoteIDs))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, s.cfg.BatchConcurrency)
+	for i, id := range quoteIDs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, id string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			items[i], docs[i] = s.batchItem(id, r)
+		}(i, id)
+	}
+	wg.Wait()
+
+	res := &BatchResult{Quotes: items}
+	ok := []*rendered{}
+	for i, item := range items {
+		if item.Success {
+			res.Succeeded++
+			ok = append(ok, docs[i])
+		} else {
+			res.Failed++
+		}
+	}
+	if res.Succeeded == 0 {
+		return res, &Error{Op: OpBatch, Err: errors.New("No worksheets were created in batch")}
+	}
+
+	var (
+		body []byte
+		fn   = batchPrefix + "batch-" + time.Now().Format("20060102-150405")
+	)
+	if r.Format == BatchMerged {
+		fn += ".pdf"
+		body, err = s.mergeDocs(ok, r.Options)
+	} else {
+		fn += ".zip"
+		body, err = zipDocs(ok)
+	}
+	if err != nil {
+		return res, &Error{Op: OpRender, Err: err}
+	}
+
+	res.File, err = s.store.Put(fn, body, identityMeta(map[string]string{}, r.Identity))
+	if err != nil {
+		return res, &Error{Op: OpUpload, Err: err}
+	}
+	s.log.Infof("Successfully created batch with key: %s, succeeded: %d, failed: %d", fn, res.Succeeded, res.Failed)
+
+	return res, nil
+}
+
+// ================================ Helper Methods
+
+func (s *Service) batchQuoteIDs(r *BatchRequest) ([]string, error) {
+
+	if len(r.QuoteIDs) > 0 {
+		return r.QuoteIDs, nil
+	}
+	if r.DateRange == nil {
+		return nil, errors.New("Batch requires either quoteIDs or a dateRange")
+	}
+	if !r.DateRange.End.After(r.DateRange.Start) {
+		return nil, errors.New("Batch dateRange end must be after start")
+	}
+
+	return s.db.FetchQuoteIDs(r.DateRange.Start, r.DateRange.End)
+}
+
+// batchItem renders a single quote, recovering from any panic so one bad quote cannot sink the batch
+func (s *Service) batchItem(quoteID string, r *BatchRequest) (item *BatchItem, doc *rendered) {
+
+	item = &BatchItem{QuoteID: quoteID}
+	defer func() {
+		if rec := recover(); rec != nil {
+			s.log.Errorf("Panic rendering quote %s: %v", quoteID, rec)
+			item.Error = fmt.Sprintf("%v", rec)
+			item.Success = false
+			doc = nil
+		}
+	}()
+
+	p, err := s.WithLogger(s.log.WithField(logger.FieldQuoteID, quoteID)).Render(&pdf.Request{Identity: r.Identity, Options: r.Options, QuoteID: quoteID})
+	if err != nil {
+		item.Error = err.Error()
+		return item, nil
+	}
+	item.Number = p.Quote().Number
+
+	body, err := p.Bytes()
+	if err != nil {
+		item.Error = err.Error()
+		return item, nil
+	}
+	item.Success = true
+
+	return item, &rendered{body: body, p: p}
+}
+
+func zipDocs(docs []*rendered) ([]byte, error) {
+
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for _, d := range docs {
+		w, err := zw.Create(d.p.FileName())
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(d.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// mergeDocs renders the worksheets again into one document, as the rendered pages cannot be imported.
+// It recovers as render does, so a quote that only panics in the merge fails the batch rather than the process
+func (s *Service) mergeDocs(docs []*rendered, opts *pdf.Options) (body []byte, err error) {
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			s.log.Errorf("Panic merging worksheets: %v", rec)
+			body = nil
+			err = fmt.Errorf("%v", rec)
+		}
+	}()
+
+	ps := make([]*pdf.PDF, len(docs))
+	for i, d := range docs {
+		ps[i] = d.p
+	}
+
+	return pdf.Merge(ps, s.cfg, opts).Bytes()
+}
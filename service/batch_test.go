@@ -0,0 +1,234 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pulpfree/univsales-wrksht-pdf/access"
+	"github.com/pulpfree/univsales-wrksht-pdf/config"
+	"github.com/pulpfree/univsales-wrksht-pdf/model"
+	"github.com/pulpfree/univsales-wrksht-pdf/pdf"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// BatchSuite struct
+// Renders batches of stub quotes, with the logo served locally
+type BatchSuite struct {
+	suite.Suite
+	db        *batchDB
+	dir       string
+	logo      *httptest.Server
+	logoFetch int32
+	s         *Service
+}
+
+// SetupTest method
+func (suite *BatchSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "wrksht")
+	suite.NoError(err)
+	suite.dir = dir
+
+	suite.logoFetch = 0
+	suite.logo = logoServer(&suite.logoFetch)
+
+	suite.db = &batchDB{quotes: map[string]*model.Quote{
+		"q1": batchQuote(1001),
+		"q2": batchQuote(1002),
+	}}
+
+	cfg := &config.Config{}
+	cfg.BatchConcurrency = 2
+	cfg.LogoURI = suite.logo.URL + "/logo.png"
+	cfg.OutputDir = dir
+	suite.s = New(cfg, suite.db)
+}
+
+// TearDownTest method
+func (suite *BatchSuite) TearDownTest() {
+	suite.logo.Close()
+	os.RemoveAll(suite.dir)
+}
+
+// TestZip method
+func (suite *BatchSuite) TestZip() {
+
+	res, err := suite.s.Batch(&BatchRequest{Identity: access.Local(), QuoteIDs: []string{"q1", "q2"}})
+	suite.NoError(err)
+	suite.Equal(2, res.Succeeded)
+	suite.Equal(0, res.Failed)
+	suite.Equal(".zip", filepath.Ext(res.File.Key))
+
+	body, err := ioutil.ReadFile(filepath.Join(suite.dir, res.File.Key))
+	suite.NoError(err)
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	suite.NoError(err)
+	suite.Len(zr.File, 2)
+	suite.Equal(int32(2), atomic.LoadInt32(&suite.logoFetch))
+}
+
+// TestMerged method
+// The merged document fetches the logo once, on top of the fetch for each rendered quote
+func (suite *BatchSuite) TestMerged() {
+
+	res, err := suite.s.Batch(&BatchRequest{Format: BatchMerged, Identity: access.Local(), QuoteIDs: []string{"q1", "q2"}})
+	suite.NoError(err)
+	suite.Equal(2, res.Succeeded)
+	suite.Equal(".pdf", filepath.Ext(res.File.Key))
+
+	body, err := ioutil.ReadFile(filepath.Join(suite.dir, res.File.Key))
+	suite.NoError(err)
+	suite.True(bytes.HasPrefix(body, []byte("%PDF")))
+	suite.Equal(int32(3), atomic.LoadInt32(&suite.logoFetch))
+}
+
+// TestItemFailure method
+// A quote that fails to fetch or panics in the render fails alone
+func (suite *BatchSuite) TestItemFailure() {
+
+	bad := batchQuote(1003)
+	bad.Customer = nil
+	suite.db.quotes["q3"] = bad
+
+	for _, format := range []string{BatchZip, BatchMerged} {
+		res, err := suite.s.Batch(&BatchRequest{Format: format, Identity: access.Local(), QuoteIDs: []string{"q1", "missing", "q3", "q2"}})
+		suite.NoError(err, format)
+		suite.Equal(2, res.Succeeded, format)
+		suite.Equal(2, res.Failed, format)
+		suite.True(res.Quotes[0].Success, format)
+		suite.False(res.Quotes[1].Success, format)
+		suite.False(res.Quotes[2].Success, format)
+		suite.NotEmpty(res.Quotes[2].Error, format)
+		suite.True(res.Quotes[3].Success, format)
+		suite.NotNil(res.File, format)
+	}
+
+	// no successful quotes
+	_, err := suite.s.Batch(&BatchRequest{Identity: access.Local(), QuoteIDs: []string{"q3"}})
+	suite.Error(err)
+}
+
+// TestMergePanic method
+// A quote that only panics in the merge fails the batch rather than the process
+func (suite *BatchSuite) TestMergePanic() {
+
+	doc := &rendered{}
+	doc.p, _ = suite.s.Render(&pdf.Request{Identity: access.Local(), QuoteID: "q1"})
+	suite.NotNil(doc.p)
+	doc.p.Quote().Customer = nil
+
+	_, err := suite.s.mergeDocs([]*rendered{doc}, nil)
+	suite.Error(err)
+}
+
+// TestRenderQuote method
+// A held quote, as from a fixture, renders without the database and a panic is returned as a render error
+func (suite *BatchSuite) TestRenderQuote() {
+
+	p, err := suite.s.RenderQuote(&pdf.Request{Identity: access.Local()}, batchQuote(1004))
+	suite.NoError(err)
+	suite.Equal(1004, p.Quote().Number)
+
+	bad := batchQuote(1005)
+	bad.Customer = nil
+	_, err = suite.s.RenderQuote(&pdf.Request{Identity: access.Local()}, bad)
+	e := &Error{}
+	suite.True(errors.As(err, &e))
+	suite.Equal(OpRender, e.Op)
+}
+
+// TestMaxBatchQuotes method
+// The limit applies to the quotes selected by a date range as well as to listed ids
+func (suite *BatchSuite) TestMaxBatchQuotes() {
+
+	for i := 0; i <= MaxBatchQuotes; i++ {
+		suite.db.ids = append(suite.db.ids, fmt.Sprintf("q%d", i))
+	}
+	end := time.Now()
+	_, err := suite.s.Batch(&BatchRequest{DateRange: &DateRange{End: end, Start: end.AddDate(0, -1, 0)}, Identity: access.Local()})
+	e := &Error{}
+	suite.True(errors.As(err, &e))
+	suite.Equal(OpBatch, e.Op)
+	suite.Equal(int32(0), atomic.LoadInt32(&suite.logoFetch))
+
+	_, err = suite.s.Batch(&BatchRequest{Identity: access.Local(), QuoteIDs: suite.db.ids})
+	suite.True(errors.As(err, &e))
+	suite.Equal(OpBatch, e.Op)
+}
+
+// TestBatchSuite function
+func TestBatchSuite(t *testing.T) {
+	suite.Run(t, new(BatchSuite))
+}
+
+// ================================ Helper Functions
+
+// logoServer serves a small png logo, counting the fetches
+func logoServer(fetches *int32) *httptest.Server {
+
+	buf := new(bytes.Buffer)
+	png.Encode(buf, image.NewRGBA(image.Rect(0, 0, 4, 4)))
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(fetches, 1)
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(buf.Bytes())
+	}))
+}
+
+func batchQuote(number int) *model.Quote {
+	dims := &model.Dims{Height: &model.Dim{Inch: 40}, Width: &model.Dim{Inch: 30}}
+	return &model.Quote{
+		Customer: &model.Customer{
+			Address:  &model.Address{City: "Welland"},
+			Email:    "test@example.com",
+			PhoneMap: map[string]string{"mobile": "905-555-1234"},
+		},
+		Items: &model.Items{
+			Window: []*model.Window{{
+				Dims:        dims,
+				ProductName: "Casement",
+				Specs:       bson.M{"installType": "Retrofit", "options": "Low E", "trim": "Brickmould"},
+			}},
+		},
+		Number: number,
+	}
+}
+
+// batchDB serves quotes by id, and the ids for any date range
+type batchDB struct {
+	ids    []string
+	quotes map[string]*model.Quote
+}
+
+func (db *batchDB) Close() {}
+
+func (db *batchDB) FetchQuote(id string) (*model.Quote, error) {
+	q, ok := db.quotes[id]
+	if !ok {
+		return nil, model.ErrQuoteNotFound
+	}
+	return q, nil
+}
+
+func (db *batchDB) FetchQuoteIDByNumber(int) (string, error) { return "", model.ErrQuoteNotFound }
+
+func (db *batchDB) FetchQuoteIDs(start, end time.Time) ([]string, error) { return db.ids, nil }
+
+func (db *batchDB) Ping(ctx context.Context) error { return nil }
+
+func (db *batchDB) WithLogger(*log.Entry) model.DBHandler { return db }
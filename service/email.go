@@ -0,0 +1,86 @@
+package service
+
+import (
+	"errors"
+	"path"
+	"strings"
+
+	"github.com/pulpfree/univsales-wrksht-pdf/access"
+	"github.com/pulpfree/univsales-wrksht-pdf/cognito"
+	"github.com/pulpfree/univsales-wrksht-pdf/email"
+	"github.com/pulpfree/univsales-wrksht-pdf/model"
+	"github.com/pulpfree/univsales-wrksht-pdf/storage"
+)
+
+// recipients expands the requested recipients, which the request identity must be allowed to email
+func (s *Service) recipients(opts *email.Options, q *model.Quote, id *cognito.Identity) ([]string, error) {
+
+	var customerEmail string
+	if q.Customer != nil {
+		customerEmail = q.Customer.Email
+	}
+
+	to := email.Recipients(opts.Recipients, customerEmail, s.cfg.InstallerEmails)
+	if len(to) == 0 {
+		return nil, errors.New("No email recipients, the customer has no email and no installers are configured")
+	}
+	if err := access.CheckRecipients(id, to, append([]string{customerEmail}, s.cfg.InstallerEmails...)); err != nil {
+		return nil, err
+	}
+
+	return to, nil
+}
+
+// sendEmail sends the stored worksheet as an attachment or a link. Links are presigned
+// with the email expiry, as they are read well after the worksheet is created
+func (s *Service) sendEmail(opts *email.Options, q *model.Quote, file *storage.FileInfo, to []string) error {
+
+	var customerName string
+	if q.Customer != nil {
+		customerName = strings.TrimSpace(q.Customer.Name.First + " " + q.Customer.Name.Last)
+	}
+
+	data := &email.TemplateData{
+		Attached:     opts.Attach,
+		CustomerName: customerName,
+		FileName:     path.Base(file.Key),
+		Number:       q.Number,
+		Revision:     q.Revision,
+	}
+	if !opts.Attach {
+		var err error
+		data.URL, data.Expires, err = s.store.URLFor(file.Key, s.cfg.EmailURLExpiry)
+		if err != nil {
+			return err
+		}
+	}
+	subject, body, err := email.Render(opts.Template, data)
+	if err != nil {
+		return err
+	}
+
+	m := &email.Message{
+		Body:    body,
+		From:    s.cfg.EmailFrom,
+		Subject: subject,
+		To:      to,
+	}
+	if opts.Attach {
+		pdfBody, err := s.store.Get(file.Key)
+		if err != nil {
+			return err
+		}
+		m.Attachment = &email.Attachment{
+			Body:        pdfBody,
+			ContentType: "application/pdf",
+			Name:        data.FileName,
+		}
+	}
+
+	if err := s.mailer.Send(m); err != nil {
+		return err
+	}
+	s.log.Infof("Emailed %s to %d recipients", file.Key, len(to))
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pulpfree/univsales-wrksht-pdf/access"
+	"github.com/pulpfree/univsales-wrksht-pdf/cognito"
+	"github.com/pulpfree/univsales-wrksht-pdf/config"
+	"github.com/pulpfree/univsales-wrksht-pdf/email"
+	"github.com/pulpfree/univsales-wrksht-pdf/model"
+	"github.com/pulpfree/univsales-wrksht-pdf/storage"
+	"github.com/stretchr/testify/suite"
+)
+
+// EmailSuite struct
+type EmailSuite struct {
+	suite.Suite
+	dir    string
+	mailer *sentMail
+	q      *model.Quote
+	s      *Service
+	store  *expiryStore
+}
+
+// SetupTest method
+func (suite *EmailSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "wrksht")
+	suite.NoError(err)
+	suite.dir = dir
+
+	cfg := &config.Config{}
+	cfg.EmailURLExpiry = 168 * time.Hour
+	cfg.InstallerEmails = []string{"crew@example.com"}
+	cfg.OutputDir = dir
+	cfg.S3URLExpiry = time.Hour
+
+	suite.mailer = &sentMail{}
+	suite.store = &expiryStore{Store: storage.New(cfg)}
+	suite.s = New(cfg, nil)
+	suite.s.mailer = suite.mailer
+	suite.s.store = suite.store
+
+	suite.q = &model.Quote{Customer: &model.Customer{Email: "jo@example.com"}, Number: 1000}
+}
+
+// TearDownTest method
+func (suite *EmailSuite) TearDownTest() {
+	os.RemoveAll(suite.dir)
+}
+
+// TestLinkExpiry method
+// Emailed links are presigned with the email expiry rather than the response url's
+func (suite *EmailSuite) TestLinkExpiry() {
+
+	file, err := suite.s.store.Put("worksheet/sht-1000.pdf", []byte("%PDF"), nil)
+	suite.NoError(err)
+
+	to, err := suite.s.recipients(&email.Options{}, suite.q, access.Local())
+	suite.NoError(err)
+	suite.NoError(suite.s.sendEmail(&email.Options{}, suite.q, file, to))
+	suite.Equal(168*time.Hour, suite.store.expiry)
+	suite.Len(suite.mailer.sent, 1)
+	suite.Equal([]string{"jo@example.com", "crew@example.com"}, suite.mailer.sent[0].To)
+
+	// attachments need no link
+	suite.store.expiry = 0
+	suite.NoError(suite.s.sendEmail(&email.Options{Attach: true}, suite.q, file, to))
+	suite.Equal(time.Duration(0), suite.store.expiry)
+	suite.NotNil(suite.mailer.sent[1].Attachment)
+}
+
+// TestRecipients method
+// Only managers, admins and services may email addresses beyond the customer and installers
+func (suite *EmailSuite) TestRecipients() {
+
+	rep := &cognito.Identity{Branches: []string{"hamilton"}, Role: access.RoleRep, UserID: "rep-1"}
+	manager := &cognito.Identity{Branches: []string{"hamilton"}, Role: access.RoleManager, UserID: "m"}
+
+	_, err := suite.s.recipients(&email.Options{Recipients: []string{email.RecipientCustomer, "crew@example.com"}}, suite.q, rep)
+	suite.NoError(err)
+
+	_, err = suite.s.recipients(&email.Options{Recipients: []string{"someone@example.com"}}, suite.q, rep)
+	suite.True(errors.Is(err, access.ErrForbidden))
+
+	to, err := suite.s.recipients(&email.Options{Recipients: []string{"someone@example.com"}}, suite.q, manager)
+	suite.NoError(err)
+	suite.Equal([]string{"someone@example.com"}, to)
+}
+
+// TestEmailSuite function
+func TestEmailSuite(t *testing.T) {
+	suite.Run(t, new(EmailSuite))
+}
+
+// expiryStore records the expiry of the last presigned url
+type expiryStore struct {
+	storage.Store
+	expiry time.Duration
+}
+
+func (s *expiryStore) URLFor(key string, expiry time.Duration) (string, time.Time, error) {
+	s.expiry = expiry
+	url, _, err := s.Store.URL(key)
+	return url, time.Now().Add(expiry), err
+}
+
+type sentMail struct {
+	sent []*email.Message
+}
+
+func (m *sentMail) Send(msg *email.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
@@ -0,0 +1,27 @@
+package service
+
+// Operation constants identifying the step that failed
+const (
+	OpBatch  = "batch"
+	OpEmail  = "email"
+	OpFetch  = "fetch"
+	OpQueue  = "queue"
+	OpRender = "render"
+	OpUpload = "upload"
+)
+
+// Error struct
+// Wraps an error with the step of the request that failed
+type Error struct {
+	Op  string
+	Err error
+}
+
+func (e *Error) Error() string {
+	return e.Op + ": " + e.Err.Error()
+}
+
+// Unwrap method
+func (e *Error) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/pulpfree/univsales-wrksht-pdf/access"
+	"github.com/pulpfree/univsales-wrksht-pdf/cognito"
+	"github.com/pulpfree/univsales-wrksht-pdf/logger"
+	"github.com/pulpfree/univsales-wrksht-pdf/model"
+	"github.com/pulpfree/univsales-wrksht-pdf/pdf"
+	"github.com/pulpfree/univsales-wrksht-pdf/queue"
+	log "github.com/sirupsen/logrus"
+)
+
+// Job kind constants
+const (
+	JobBatch     = "batch"
+	JobWorksheet = "worksheet"
+)
+
+// UseJobs method
+// Sets the job store and queue used for asynchronous requests
+func (s *Service) UseJobs(jobs model.JobStore, q queue.Queue) *Service {
+	s.jobs = jobs
+	s.queue = q
+	return s
+}
+
+// Enqueue method
+// Stores a queued job for the request of id and sends its id to the queue
+func (s *Service) Enqueue(kind string, r interface{}, id *cognito.Identity) (*model.Job, error) {
+
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &model.Job{
+		Kind:    kind,
+		Payload: string(payload),
+		Status:  model.JobQueued,
+		UserID:  userID(id),
+	}
+	if err := s.jobs.CreateJob(j); err != nil {
+		return nil, &Error{Op: OpQueue, Err: err}
+	}
+
+	if err := s.queue.Send(j.ID.Hex()); err != nil {
+		j.Status = model.JobFailed
+		j.Error = err.Error()
+		if uErr := s.jobs.UpdateJob(j); uErr != nil {
+			s.log.Errorf("Error updating job %s: %s", j.ID.Hex(), uErr)
+		}
+		return j, &Error{Op: OpQueue, Err: err}
+	}
+	s.log.Infof("Queued %s job: %s", kind, j.ID.Hex())
+
+	return j, nil
+}
+
+// Job method
+// Returns the job when id may see it, with a fresh download url once it is done
+func (s *Service) Job(jobID string, id *cognito.Identity) (*model.Job, error) {
+
+	j, err := s.jobs.FetchJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+	if err := access.CheckJob(id, j); err != nil {
+		return nil, err
+	}
+
+	if j.Status == model.JobDone && j.Key != "" {
+		j.URL, _, err = s.store.URL(j.Key)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return j, nil
+}
+
+// RunJob method
+// Runs a queued job through the same path as the synchronous requests and records the outcome.
+// A failed job is recorded on the job rather than returned, so the queue does not redeliver it.
+func (s *Service) RunJob(jobID string) error {
+
+	s = s.WithLogger(s.log.WithField(logger.FieldJobID, jobID))
+
+	j, err := s.jobs.FetchJob(jobID)
+	if err != nil {
+		return err
+	}
+
+	// Queues deliver at least once, so skip jobs that have already finished
+	if j.Status == model.JobDone || j.Status == model.JobFailed {
+		s.log.Warnf("Skipping job %s with status: %s", jobID, j.Status)
+		return nil
+	}
+
+	j.Status = model.JobRunning
+	if err := s.jobs.UpdateJob(j); err != nil {
+		return err
+	}
+
+	err = s.runJob(j)
+	if err != nil {
+		s.log.Errorf("Job %s failed: %s", jobID, err)
+		j.Status = model.JobFailed
+		j.Error = err.Error()
+	} else {
+		j.Status = model.JobDone
+	}
+
+	return s.jobs.UpdateJob(j)
+}
+
+// ================================ Helper Methods
+
+func (s *Service) runJob(j *model.Job) (err error) {
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("Panic running job: %v", rec)
+		}
+	}()
+
+	switch j.Kind {
+	case JobWorksheet:
+		r := &pdf.Request{}
+		if err := json.Unmarshal([]byte(j.Payload), r); err != nil {
+			return err
+		}
+		l := s.log.WithFields(log.Fields{logger.FieldPrincipal: r.Principal, logger.FieldQuoteID: r.QuoteID})
+		file, err := s.WithLogger(l).Create(r)
+		if err != nil {
+			return err
+		}
+		j.Key = file.Key
+
+	case JobBatch:
+		r := &BatchRequest{}
+		if err := json.Unmarshal([]byte(j.Payload), r); err != nil {
+			return err
+		}
+		res, err := s.Batch(r)
+		if res != nil {
+			for _, item := range res.Quotes {
+				if !item.Success {
+					j.Failures = append(j.Failures, fmt.Sprintf("%s: %s", item.QuoteID, item.Error))
+				}
+			}
+			if res.File != nil {
+				j.Key = res.File.Key
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+	default:
+		return errors.New("Unknown job kind: " + j.Kind)
+	}
+
+	return nil
+}
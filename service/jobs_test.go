@@ -0,0 +1,220 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pulpfree/univsales-wrksht-pdf/access"
+	"github.com/pulpfree/univsales-wrksht-pdf/cognito"
+	"github.com/pulpfree/univsales-wrksht-pdf/config"
+	"github.com/pulpfree/univsales-wrksht-pdf/model"
+	"github.com/pulpfree/univsales-wrksht-pdf/pdf"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// JobsSuite struct
+type JobsSuite struct {
+	suite.Suite
+	dir   string
+	jobs  *jobStore
+	logo  *httptest.Server
+	queue *sendQueue
+	s     *Service
+}
+
+// SetupTest method
+func (suite *JobsSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "wrksht")
+	suite.NoError(err)
+	suite.dir = dir
+
+	var fetches int32
+	suite.logo = logoServer(&fetches)
+
+	cfg := &config.Config{}
+	cfg.BatchConcurrency = 2
+	cfg.LogoURI = suite.logo.URL + "/logo.png"
+	cfg.OutputDir = dir
+	cfg.OutputURL = "http://localhost/files/"
+	db := &batchDB{quotes: map[string]*model.Quote{"q1": batchQuote(1001), "q2": batchQuote(1002)}}
+	suite.jobs = &jobStore{jobs: map[string]*model.Job{}}
+	suite.queue = &sendQueue{}
+	suite.s = New(cfg, db).UseJobs(suite.jobs, suite.queue)
+}
+
+// TearDownTest method
+func (suite *JobsSuite) TearDownTest() {
+	suite.logo.Close()
+	os.RemoveAll(suite.dir)
+}
+
+// TestEnqueue method
+func (suite *JobsSuite) TestEnqueue() {
+
+	owner := &cognito.Identity{Branches: []string{"hamilton"}, Role: access.RoleRep, UserID: "rep-1"}
+	j, err := suite.s.Enqueue(JobBatch, &BatchRequest{Identity: owner, QuoteIDs: []string{"q1", "q2"}}, owner)
+	suite.NoError(err)
+	suite.Equal(model.JobQueued, j.Status)
+	suite.Equal(JobBatch, j.Kind)
+	suite.Equal("rep-1", j.UserID)
+	suite.Equal([]string{j.ID.Hex()}, suite.queue.sent)
+
+	r := &BatchRequest{}
+	suite.NoError(json.Unmarshal([]byte(j.Payload), r))
+	suite.Equal([]string{"q1", "q2"}, r.QuoteIDs)
+	suite.Equal("rep-1", r.Identity.UserID)
+
+	// a job the queue rejects is recorded as failed
+	suite.queue.err = errors.New("queue full")
+	j, err = suite.s.Enqueue(JobWorksheet, &pdf.Request{Identity: owner, QuoteID: "q1"}, owner)
+	e := &Error{}
+	suite.True(errors.As(err, &e))
+	suite.Equal(OpQueue, e.Op)
+	suite.Equal(model.JobFailed, suite.jobs.jobs[j.ID.Hex()].Status)
+	suite.Equal("queue full", suite.jobs.jobs[j.ID.Hex()].Error)
+}
+
+// TestRunJob method
+func (suite *JobsSuite) TestRunJob() {
+
+	j, err := suite.s.Enqueue(JobWorksheet, &pdf.Request{Identity: access.Local(), QuoteID: "q1"}, access.Local())
+	suite.NoError(err)
+	suite.NoError(suite.s.RunJob(j.ID.Hex()))
+	suite.Equal(model.JobDone, j.Status)
+	suite.NotEmpty(j.Key)
+	suite.FileExists(filepath.Join(suite.dir, j.Key))
+
+	// the failed quotes of a batch are listed on the job
+	j, err = suite.s.Enqueue(JobBatch, &BatchRequest{Identity: access.Local(), QuoteIDs: []string{"q1", "missing"}}, access.Local())
+	suite.NoError(err)
+	suite.NoError(suite.s.RunJob(j.ID.Hex()))
+	suite.Equal(model.JobDone, j.Status)
+	suite.Len(j.Failures, 1)
+	suite.Contains(j.Failures[0], "missing")
+
+	err = suite.s.RunJob(primitive.NewObjectID().Hex())
+	suite.True(errors.Is(err, model.ErrJobNotFound))
+}
+
+// TestRunJobFailed method
+// A failed job is recorded on the job, rather than returned for the queue to redeliver
+func (suite *JobsSuite) TestRunJobFailed() {
+
+	tests := []struct {
+		name string
+		kind string
+		r    interface{}
+	}{
+		{"missing quote", JobWorksheet, &pdf.Request{Identity: access.Local(), QuoteID: "missing"}},
+		{"forbidden quote", JobWorksheet, &pdf.Request{QuoteID: "q1"}},
+		{"no quotes rendered", JobBatch, &BatchRequest{Identity: access.Local(), QuoteIDs: []string{"missing"}}},
+		{"unknown kind", "report", &pdf.Request{Identity: access.Local(), QuoteID: "q1"}},
+	}
+
+	for _, tt := range tests {
+		j, err := suite.s.Enqueue(tt.kind, tt.r, access.Local())
+		suite.NoError(err, tt.name)
+		suite.NoError(suite.s.RunJob(j.ID.Hex()), tt.name)
+		suite.Equal(model.JobFailed, j.Status, tt.name)
+		suite.NotEmpty(j.Error, tt.name)
+		suite.Empty(j.Key, tt.name)
+	}
+}
+
+// TestRunJobSkipsFinished method
+// Queues deliver at least once, so a finished job is not run again
+func (suite *JobsSuite) TestRunJobSkipsFinished() {
+
+	for _, status := range []model.JobStatus{model.JobDone, model.JobFailed} {
+		j, err := suite.s.Enqueue(JobWorksheet, &pdf.Request{Identity: access.Local(), QuoteID: "q1"}, access.Local())
+		suite.NoError(err)
+		j.Key = "worksheet/earlier.pdf"
+		j.Status = status
+		updates := suite.jobs.updates
+
+		suite.NoError(suite.s.RunJob(j.ID.Hex()))
+		suite.Equal(status, j.Status)
+		suite.Equal("worksheet/earlier.pdf", j.Key)
+		suite.Equal(updates, suite.jobs.updates)
+	}
+}
+
+// TestJob method
+// Only the user who queued a job, or an admin, gets its download url
+func (suite *JobsSuite) TestJob() {
+
+	owner := &cognito.Identity{Branches: []string{"hamilton"}, Role: access.RoleRep, UserID: "rep-1"}
+	j, err := suite.s.Enqueue(JobWorksheet, &pdf.Request{Identity: owner, QuoteID: "q1"}, owner)
+	suite.NoError(err)
+	suite.Equal("rep-1", j.UserID)
+
+	j.Key = "wrksht/sht-1042-r1.pdf"
+	j.Status = model.JobDone
+
+	got, err := suite.s.Job(j.ID.Hex(), owner)
+	suite.NoError(err)
+	suite.Equal("http://localhost/files/wrksht/sht-1042-r1.pdf", got.URL)
+
+	got, err = suite.s.Job(j.ID.Hex(), &cognito.Identity{Branches: []string{"hamilton"}, Role: access.RoleManager, UserID: "m"})
+	suite.True(errors.Is(err, access.ErrForbidden))
+	suite.Nil(got)
+
+	_, err = suite.s.Job(j.ID.Hex(), nil)
+	suite.True(errors.Is(err, access.ErrForbidden))
+
+	got, err = suite.s.Job(j.ID.Hex(), &cognito.Identity{Role: access.RoleAdmin, UserID: "a"})
+	suite.NoError(err)
+	suite.NotEmpty(got.URL)
+}
+
+// ================================ Helper Methods
+
+type jobStore struct {
+	jobs    map[string]*model.Job
+	updates int
+}
+
+func (s *jobStore) Close() {}
+
+func (s *jobStore) CreateJob(j *model.Job) error {
+	j.ID = primitive.NewObjectID()
+	s.jobs[j.ID.Hex()] = j
+	return nil
+}
+
+func (s *jobStore) FetchJob(jobID string) (*model.Job, error) {
+	j, ok := s.jobs[jobID]
+	if !ok {
+		return nil, model.ErrJobNotFound
+	}
+	return j, nil
+}
+
+func (s *jobStore) UpdateJob(j *model.Job) error {
+	s.updates++
+	return nil
+}
+
+type sendQueue struct {
+	err  error
+	sent []string
+}
+
+func (q *sendQueue) Send(jobID string) error {
+	if q.err != nil {
+		return q.err
+	}
+	q.sent = append(q.sent, jobID)
+	return nil
+}
+
+// TestJobsSuite function
+func TestJobsSuite(t *testing.T) {
+	suite.Run(t, new(JobsSuite))
+}
@@ -0,0 +1,285 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pulpfree/univsales-wrksht-pdf/access"
+	"github.com/pulpfree/univsales-wrksht-pdf/cognito"
+	"github.com/pulpfree/univsales-wrksht-pdf/config"
+	"github.com/pulpfree/univsales-wrksht-pdf/email"
+	"github.com/pulpfree/univsales-wrksht-pdf/logger"
+	"github.com/pulpfree/univsales-wrksht-pdf/model"
+	"github.com/pulpfree/univsales-wrksht-pdf/notify"
+	"github.com/pulpfree/univsales-wrksht-pdf/pdf"
+	"github.com/pulpfree/univsales-wrksht-pdf/queue"
+	"github.com/pulpfree/univsales-wrksht-pdf/storage"
+	"github.com/pulpfree/univsales-wrksht-pdf/tracing"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Service struct
+// Ties together the quote fetch, worksheet render and upload steps
+type Service struct {
+	cfg    *config.Config
+	db     model.DBHandler
+	events notify.Publisher
+	jobs   model.JobStore
+	log    *log.Entry
+	mailer email.Sender
+	queue  queue.Queue
+	store  storage.Store
+}
+
+// New function
+func New(cfg *config.Config, db model.DBHandler) *Service {
+	return &Service{
+		cfg:    cfg,
+		db:     db,
+		events: notify.New(cfg),
+		log:    logger.Default(),
+		mailer: email.New(cfg),
+		store:  storage.New(cfg),
+	}
+}
+
+// WithLogger method
+// Returns a copy of the service logging to l, including the database and render log lines
+func (s *Service) WithLogger(l *log.Entry) *Service {
+	c := *s
+	c.log = l
+	if s.db != nil {
+		c.db = s.db.WithLogger(l)
+	}
+	return &c
+}
+
+// Render method
+// Fetches the requested quote and renders the worksheet
+func (s *Service) Render(r *pdf.Request) (*pdf.PDF, error) {
+
+	q, err := s.fetch(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.render(r, q)
+}
+
+// RenderQuote method
+// Renders the worksheet of a quote the caller already holds, such as a fixture, recovering as Render does
+func (s *Service) RenderQuote(r *pdf.Request, q *model.Quote) (*pdf.PDF, error) {
+	return s.render(r, q)
+}
+
+// Create method
+// Renders the requested worksheet and stores it. When the stored worksheet was rendered
+// from an unchanged quote it is returned instead, unless the request forces a new render.
+// The worksheet is then emailed when the request has email options
+func (s *Service) Create(r *pdf.Request) (*storage.FileInfo, error) {
+
+	q, err := s.fetch(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// the recipients are checked before the render, so a refused email stores nothing
+	var to []string
+	if r.Email != nil {
+		to, err = s.recipients(r.Email, q, r.Identity)
+		if err != nil {
+			return nil, &Error{Op: OpEmail, Err: err}
+		}
+	}
+
+	file, err := s.create(r, q)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Email != nil {
+		if err := s.sendEmail(r.Email, q, file, to); err != nil {
+			return nil, &Error{Op: OpEmail, Err: err}
+		}
+	}
+
+	return file, nil
+}
+
+// Save method
+// Stores a rendered worksheet as a new version
+func (s *Service) Save(p *pdf.PDF) (*storage.FileInfo, error) {
+
+	fp, err := Fingerprint(p.Quote(), p.Options())
+	if err != nil {
+		return nil, &Error{Op: OpRender, Err: err}
+	}
+
+	return s.save(p, identityMeta(map[string]string{storage.MetaFingerprint: fp}, p.Request.Identity))
+}
+
+// Fingerprint function
+// Returns a hash of the quote content that appears on the worksheet and the options it is rendered with
+func Fingerprint(q *model.Quote, opts *pdf.Options) (string, error) {
+
+	body, err := json.Marshal(struct {
+		Customer  *model.Customer
+		Features  string
+		Fees      interface{}
+		Items     *model.Items
+		Number    int
+		Options   *pdf.Options
+		Revision  int
+		UpdatedAt time.Time
+	}{
+		Customer:  q.Customer,
+		Features:  q.Features,
+		Fees:      q.Fees,
+		Items:     q.Items,
+		Number:    q.Number,
+		Options:   opts.Normalize(),
+		Revision:  q.Revision,
+		UpdatedAt: q.UpdatedAt,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ================================ Helper Methods
+
+// fetch returns the requested quote, when the request identity may see it
+func (s *Service) fetch(r *pdf.Request) (*model.Quote, error) {
+
+	q, err := access.NewDB(s.db, r.Identity).FetchQuote(r.QuoteID)
+	if err != nil {
+		return nil, &Error{Op: OpFetch, Err: err}
+	}
+
+	return q, nil
+}
+
+func (s *Service) create(r *pdf.Request, q *model.Quote) (*storage.FileInfo, error) {
+
+	fp, err := Fingerprint(q, r.Options)
+	if err != nil {
+		return nil, &Error{Op: OpRender, Err: err}
+	}
+
+	if !r.Force {
+		file, err := s.existing(q.Number, fp, r.Identity)
+		if err != nil {
+			return nil, &Error{Op: OpUpload, Err: err}
+		}
+		if file != nil {
+			s.log.Infof("Reusing unchanged PDF with key: %s", file.Key)
+			return file, nil
+		}
+	}
+
+	p, err := s.render(r, q)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.save(p, identityMeta(map[string]string{storage.MetaFingerprint: fp}, r.Identity))
+}
+
+// render recovers from unexpected quote data panicking in the worksheet sections
+func (s *Service) render(r *pdf.Request, q *model.Quote) (p *pdf.PDF, err error) {
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			p = nil
+			err = &Error{Op: OpRender, Err: fmt.Errorf("%v", rec)}
+		}
+	}()
+
+	p = pdf.New(r, q, s.cfg).UseLogger(s.log)
+	err = p.WorkSheet()
+	if err != nil {
+		return nil, &Error{Op: OpRender, Err: err}
+	}
+
+	return p, nil
+}
+
+func (s *Service) save(p *pdf.PDF, meta map[string]string) (*storage.FileInfo, error) {
+
+	body, err := p.Bytes()
+	if err != nil {
+		return nil, &Error{Op: OpRender, Err: err}
+	}
+
+	_, span := tracing.Start(tracing.Context(s.log), "storage put",
+		attribute.String("storage.key", p.OutputFileName()),
+		attribute.Int("storage.size", len(body)),
+	)
+	file, err := s.store.Put(p.OutputFileName(), body, meta)
+	tracing.End(span, err)
+	if err != nil {
+		return nil, &Error{Op: OpUpload, Err: err}
+	}
+	if err := s.setLatest(p, file); err != nil {
+		return nil, &Error{Op: OpUpload, Err: err}
+	}
+	s.log.Infof("Successfully created PDF with key: %s", file.Key)
+
+	s.publish(p, file)
+
+	// a failed prune leaves extra versions for the next run, the new version is already stored
+	if _, err := s.Prune(p.Quote().Number); err != nil {
+		s.log.Errorf("Failed to prune worksheet versions: %s", err)
+	}
+
+	return file, nil
+}
+
+// publish announces a stored worksheet, a failure is logged as the worksheet itself is already stored
+func (s *Service) publish(p *pdf.PDF, file *storage.FileInfo) {
+
+	q := p.Quote()
+	err := s.events.Publish(&notify.Event{
+		Fingerprint: file.Fingerprint,
+		Key:         file.Key,
+		Number:      q.Number,
+		Principal:   p.Request.Principal,
+		QuoteID:     q.ID.Hex(),
+		Revision:    q.Revision,
+		Time:        time.Now(),
+		Type:        notify.WorksheetGenerated,
+	})
+	if err != nil {
+		s.log.Errorf("Failed to publish %s event for key %s: %s", notify.WorksheetGenerated, file.Key, err)
+	}
+}
+
+// ================================ Helper Functions
+
+// userID returns the id identityMeta stores for id, empty without an identity
+func userID(id *cognito.Identity) string {
+	if id == nil {
+		return ""
+	}
+	return id.UserID
+}
+
+// identityMeta adds the requesting user to the stored object metadata. The name is left out
+// as object metadata only holds ascii
+func identityMeta(meta map[string]string, id *cognito.Identity) map[string]string {
+	if id == nil {
+		return meta
+	}
+	meta[storage.MetaUserID] = id.UserID
+	if id.Email != "" {
+		meta[storage.MetaUserEmail] = id.Email
+	}
+	return meta
+}
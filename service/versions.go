@@ -0,0 +1,254 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pulpfree/univsales-wrksht-pdf/access"
+	"github.com/pulpfree/univsales-wrksht-pdf/cognito"
+	"github.com/pulpfree/univsales-wrksht-pdf/pdf"
+	"github.com/pulpfree/univsales-wrksht-pdf/storage"
+)
+
+// worksheetPrefix is shared by the version prefixes of every quote, see pdf.KeyPrefix
+const worksheetPrefix = "worksheet/sht-"
+
+// versionPattern matches the revision and creation time of a version key, see pdf.setOutputFileName
+var versionPattern = regexp.MustCompile(`-r(\d+)-([^/]+)\.pdf$`)
+
+// Latest struct
+// Stored at pdf.LatestKey, points to the most recent version of a quote's worksheet
+type Latest struct {
+	Fingerprint string    `json:"fingerprint"`
+	Key         string    `json:"key"`
+	Revision    int       `json:"revision"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// Latest method
+// Returns the pointer to the latest version of the worksheet for quote number
+func (s *Service) Latest(number int) (*Latest, error) {
+
+	body, err := s.store.Get(pdf.LatestKey(number))
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Latest{}
+	if err := json.Unmarshal(body, l); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Worksheet method
+// Returns the latest stored version of the worksheet for quote number with a fresh download url,
+// when id may see the quote
+func (s *Service) Worksheet(number int, id *cognito.Identity) (*storage.FileInfo, error) {
+
+	quoteID, err := s.db.FetchQuoteIDByNumber(number)
+	if err != nil {
+		return nil, &Error{Op: OpFetch, Err: err}
+	}
+	if _, err := access.NewDB(s.db, id).FetchQuote(quoteID); err != nil {
+		return nil, &Error{Op: OpFetch, Err: err}
+	}
+
+	l, err := s.Latest(number)
+	if err != nil {
+		return nil, err
+	}
+	file, err := s.store.Stat(l.Key)
+	if err != nil {
+		return nil, err
+	}
+	file.URL, file.Expires, err = s.store.URL(l.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// Prune method
+// Deletes all but the configured number of most recent versions of a quote's worksheet
+func (s *Service) Prune(number int) ([]string, error) {
+
+	files, err := s.store.List(pdf.KeyPrefix(number))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.pruneVersions(pdf.KeyPrefix(number), files)
+}
+
+// PruneAll method
+// Applies the retention policy to the worksheets of every quote
+func (s *Service) PruneAll() ([]string, error) {
+
+	files, err := s.store.List(worksheetPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	// unversioned worksheets stored directly under worksheet/ are left alone
+	quotes := map[string][]*storage.FileInfo{}
+	for _, f := range files {
+		dir := path.Dir(f.Key) + "/"
+		if strings.HasPrefix(dir, worksheetPrefix) {
+			quotes[dir] = append(quotes[dir], f)
+		}
+	}
+
+	deleted := []string{}
+	for prefix, qf := range quotes {
+		keys, err := s.pruneVersions(prefix, qf)
+		if err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, keys...)
+	}
+
+	return deleted, nil
+}
+
+// ================================ Helper Methods
+
+// setLatest points the quote's latest key at a newly stored version
+func (s *Service) setLatest(p *pdf.PDF, file *storage.FileInfo) error {
+
+	body, err := json.Marshal(&Latest{
+		Fingerprint: file.Fingerprint,
+		Key:         file.Key,
+		Revision:    p.Quote().Revision,
+		UpdatedAt:   time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.store.Put(pdf.LatestKey(p.Quote().Number), body, nil)
+	return err
+}
+
+// existing returns the latest stored version when it has a matching fingerprint and was generated
+// for the same user, as the footer and metadata name the user, otherwise nil
+func (s *Service) existing(number int, fp string, id *cognito.Identity) (*storage.FileInfo, error) {
+
+	l, err := s.Latest(number)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if l.Fingerprint != fp {
+		return nil, nil
+	}
+
+	file, err := s.store.Stat(l.Key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if file.Meta[storage.MetaUserID] != userID(id) {
+		return nil, nil
+	}
+
+	file.URL, file.Expires, err = s.store.URL(l.Key)
+	if err != nil {
+		return nil, err
+	}
+	file.Reused = true
+
+	return file, nil
+}
+
+// pruneVersions deletes the oldest versions under prefix beyond the retention limit
+// the version the latest pointer refers to is always kept
+func (s *Service) pruneVersions(prefix string, files []*storage.FileInfo) ([]string, error) {
+
+	if s.cfg.RetainVersions == 0 {
+		return nil, nil
+	}
+
+	versions := []*version{}
+	for _, f := range files {
+		if path.Ext(f.Key) == ".pdf" {
+			versions = append(versions, newVersion(f))
+		}
+	}
+	if len(versions) <= s.cfg.RetainVersions {
+		return nil, nil
+	}
+
+	// newest first, by revision and then creation time, as the keys do not sort by revision past r9
+	sort.SliceStable(versions, func(i, j int) bool { return versions[i].newer(versions[j]) })
+
+	var latest string
+	body, err := s.store.Get(prefix + pdf.LatestFile)
+	if err == nil {
+		l := &Latest{}
+		if json.Unmarshal(body, l) == nil {
+			latest = l.Key
+		}
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+
+	deleted := []string{}
+	for _, v := range versions[s.cfg.RetainVersions:] {
+		if v.key != latest {
+			deleted = append(deleted, v.key)
+		}
+	}
+	if len(deleted) == 0 {
+		return nil, nil
+	}
+
+	if err := s.store.Delete(deleted...); err != nil {
+		return nil, err
+	}
+	s.log.Infof("Pruned %d worksheet versions under %s", len(deleted), prefix)
+
+	return deleted, nil
+}
+
+// ================================ Helper Functions
+
+// version is a stored worksheet version, keys that do not match versionPattern sort as the oldest
+type version struct {
+	created  string
+	key      string
+	modified time.Time
+	revision int
+}
+
+func newVersion(f *storage.FileInfo) *version {
+	v := &version{key: f.Key, modified: f.Modified, revision: -1}
+	if m := versionPattern.FindStringSubmatch(f.Key); m != nil {
+		v.revision, _ = strconv.Atoi(m[1])
+		v.created = m[2]
+	}
+	return v
+}
+
+func (v *version) newer(o *version) bool {
+	if v.revision != o.revision {
+		return v.revision > o.revision
+	}
+	if v.created != o.created {
+		// the fixed width creation time sorts in time order
+		return v.created > o.created
+	}
+	return v.modified.After(o.modified)
+}
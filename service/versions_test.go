@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pulpfree/univsales-wrksht-pdf/access"
+	"github.com/pulpfree/univsales-wrksht-pdf/cognito"
+	"github.com/pulpfree/univsales-wrksht-pdf/config"
+	"github.com/pulpfree/univsales-wrksht-pdf/model"
+	"github.com/pulpfree/univsales-wrksht-pdf/pdf"
+	"github.com/pulpfree/univsales-wrksht-pdf/storage"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+)
+
+// VersionsSuite struct
+type VersionsSuite struct {
+	suite.Suite
+	dir string
+	s   *Service
+}
+
+// SetupTest method
+func (suite *VersionsSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "wrksht")
+	suite.NoError(err)
+	suite.dir = dir
+
+	cfg := &config.Config{}
+	cfg.OutputDir = dir
+	cfg.RetainVersions = 2
+	suite.s = New(cfg, nil)
+}
+
+// TearDownTest method
+func (suite *VersionsSuite) TearDownTest() {
+	os.RemoveAll(suite.dir)
+}
+
+// TestPrune method
+func (suite *VersionsSuite) TestPrune() {
+
+	keys := suite.putVersions(1000, 4)
+	suite.putVersions(1001, 1)
+
+	// the latest pointer refers to the oldest version, which must survive
+	body, _ := json.Marshal(&Latest{Key: keys[0]})
+	_, err := suite.s.store.Put(pdf.LatestKey(1000), body, nil)
+	suite.NoError(err)
+
+	deleted, err := suite.s.PruneAll()
+	suite.NoError(err)
+	suite.Equal([]string{keys[1]}, deleted)
+
+	files, err := suite.s.store.List(pdf.KeyPrefix(1000))
+	suite.NoError(err)
+	suite.Len(files, 4)
+
+	files, err = suite.s.store.List(pdf.KeyPrefix(1001))
+	suite.NoError(err)
+	suite.Len(files, 1)
+}
+
+// TestPruneKeepAll method
+func (suite *VersionsSuite) TestPruneKeepAll() {
+
+	suite.s.cfg.RetainVersions = 0
+	suite.putVersions(1000, 4)
+
+	deleted, err := suite.s.Prune(1000)
+	suite.NoError(err)
+	suite.Empty(deleted)
+}
+
+// TestExisting method
+// A stored worksheet names the user it was generated for, so it is only reused for that user
+func (suite *VersionsSuite) TestExisting() {
+
+	jane := &cognito.Identity{Email: "jane@universalwindows.ca", Name: "Jane Doe", UserID: "8f1c-42"}
+	key := suite.putVersions(1000, 1)[0]
+	_, err := suite.s.store.Put(key, []byte("pdf"), identityMeta(map[string]string{storage.MetaFingerprint: "fp"}, jane))
+	suite.NoError(err)
+	body, _ := json.Marshal(&Latest{Fingerprint: "fp", Key: key})
+	_, err = suite.s.store.Put(pdf.LatestKey(1000), body, nil)
+	suite.NoError(err)
+
+	file, err := suite.s.existing(1000, "fp", jane)
+	suite.NoError(err)
+	suite.True(file.Reused)
+	suite.Equal(key, file.Key)
+
+	for _, id := range []*cognito.Identity{{Name: "John Roe", UserID: "77aa-01"}, nil} {
+		file, err = suite.s.existing(1000, "fp", id)
+		suite.NoError(err)
+		suite.Nil(file)
+	}
+
+	file, err = suite.s.existing(1000, "changed", jane)
+	suite.NoError(err)
+	suite.Nil(file)
+}
+
+// TestWorksheet method
+// The latest version is returned to the users who may see the quote
+func (suite *VersionsSuite) TestWorksheet() {
+
+	suite.s.db = &quoteDB{q: &model.Quote{Branch: "hamilton", Number: 1000}}
+	key := suite.putVersions(1000, 2)[1]
+	body, _ := json.Marshal(&Latest{Fingerprint: "fp", Key: key})
+	_, err := suite.s.store.Put(pdf.LatestKey(1000), body, nil)
+	suite.NoError(err)
+
+	file, err := suite.s.Worksheet(1000, &cognito.Identity{Branches: []string{"hamilton"}, UserID: "i"})
+	suite.NoError(err)
+	suite.Equal(key, file.Key)
+	suite.NotEmpty(file.URL)
+
+	_, err = suite.s.Worksheet(1000, &cognito.Identity{Branches: []string{"burlington"}, UserID: "i"})
+	suite.True(errors.Is(err, access.ErrForbidden))
+	_, err = suite.s.Worksheet(1000, nil)
+	suite.True(errors.Is(err, access.ErrForbidden))
+
+	// a quote without a stored worksheet
+	suite.s.db = &quoteDB{q: &model.Quote{Branch: "hamilton", Number: 1001}}
+	_, err = suite.s.Worksheet(1001, &cognito.Identity{Branches: []string{"hamilton"}, UserID: "i"})
+	suite.True(errors.Is(err, storage.ErrNotFound))
+}
+
+// TestPruneRevisions method
+// Revision 10 sorts before revision 9 as a string, but is the newer version
+func (suite *VersionsSuite) TestPruneRevisions() {
+
+	keys := suite.putRevisions(1000, 8, 4)
+
+	deleted, err := suite.s.Prune(1000)
+	suite.NoError(err)
+	suite.ElementsMatch([]string{keys[0], keys[1]}, deleted)
+
+	files, err := suite.s.store.List(pdf.KeyPrefix(1000))
+	suite.NoError(err)
+	left := []string{}
+	for _, f := range files {
+		left = append(left, f.Key)
+	}
+	suite.ElementsMatch([]string{keys[2], keys[3]}, left)
+}
+
+// putVersions stores n versions of a quote's worksheet, oldest first
+func (suite *VersionsSuite) putVersions(number, n int) []string {
+	return suite.putRevisions(number, 0, n)
+}
+
+// putRevisions stores n versions of a quote's worksheet from revision from, oldest first
+func (suite *VersionsSuite) putRevisions(number, from, n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("%ssht-%d-r%d-20201005T1200%02d.000Z.pdf", pdf.KeyPrefix(number), number, from+i, i)
+		_, err := suite.s.store.Put(keys[i], []byte("pdf"), nil)
+		suite.NoError(err)
+	}
+	return keys
+}
+
+type quoteDB struct {
+	q *model.Quote
+}
+
+func (db *quoteDB) Close() {}
+
+func (db *quoteDB) FetchQuote(string) (*model.Quote, error) { return db.q, nil }
+
+func (db *quoteDB) FetchQuoteIDByNumber(int) (string, error) { return "q1", nil }
+
+func (db *quoteDB) FetchQuoteIDs(start, end time.Time) ([]string, error) { return nil, nil }
+
+func (db *quoteDB) Ping(ctx context.Context) error { return nil }
+
+func (db *quoteDB) WithLogger(*log.Entry) model.DBHandler { return db }
+
+// TestVersionsSuite function
+func TestVersionsSuite(t *testing.T) {
+	suite.Run(t, new(VersionsSuite))
+}
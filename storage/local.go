@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pulpfree/univsales-wrksht-pdf/metrics"
+)
+
+// metaExt is appended to the file path for the sidecar file holding the metadata
+const metaExt = ".meta.json"
+
+// Local struct
+// Stores files in a local directory, used when running outside of AWS
+type Local struct {
+	baseURL string
+	dir     string
+	metrics metrics.Recorder
+}
+
+// NewLocal function
+// When baseURL is empty file urls point directly at the file on disk
+func NewLocal(dir, baseURL string) *Local {
+	return &Local{
+		baseURL: baseURL,
+		dir:     dir,
+		metrics: metrics.Default(),
+	}
+}
+
+// Delete method
+func (l *Local) Delete(keys ...string) error {
+
+	for _, key := range keys {
+		fp := l.path(key)
+		if err := os.Remove(fp); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err := os.Remove(fp + metaExt); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Get method
+func (l *Local) Get(key string) ([]byte, error) {
+
+	body, err := ioutil.ReadFile(l.path(key))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+
+	return body, err
+}
+
+// List method
+// Metadata sidecar files are not included in the listing
+func (l *Local) List(prefix string) ([]*FileInfo, error) {
+
+	files := []*FileInfo{}
+	err := filepath.Walk(l.dir, func(fp string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if fi.IsDir() || strings.HasSuffix(fp, metaExt) {
+			return nil
+		}
+		rel, err := filepath.Rel(l.dir, fp)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if strings.HasPrefix(key, prefix) {
+			files = append(files, &FileInfo{Key: key, Modified: fi.ModTime(), Size: fi.Size()})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// Put method
+func (l *Local) Put(key string, body []byte, meta map[string]string) (*FileInfo, error) {
+
+	start := time.Now()
+	fp := l.path(key)
+	if err := os.MkdirAll(filepath.Dir(fp), 0755); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(fp, body, 0644); err != nil {
+		return nil, err
+	}
+
+	info := newFileInfo(key, body, meta)
+	metaBody, err := json.Marshal(info.Meta)
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(fp+metaExt, metaBody, 0644); err != nil {
+		return nil, err
+	}
+	recordPut(l.metrics, key, start, body)
+
+	info.URL, info.Expires, err = l.URL(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// Stat method
+func (l *Local) Stat(key string) (*FileInfo, error) {
+
+	fp := l.path(key)
+	fi, err := os.Stat(fp)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	meta := map[string]string{}
+	if metaBody, err := ioutil.ReadFile(fp + metaExt); err == nil {
+		if err := json.Unmarshal(metaBody, &meta); err != nil {
+			return nil, err
+		}
+	}
+
+	info := &FileInfo{Key: key, Size: fi.Size()}
+	info.setMeta(meta)
+
+	return info, nil
+}
+
+// URLFor method
+// Local files do not expire, so the expiry is ignored
+func (l *Local) URLFor(key string, expiry time.Duration) (string, time.Time, error) {
+	return l.URL(key)
+}
+
+// URL method
+// Local files do not expire, so the returned time is always zero
+func (l *Local) URL(key string) (string, time.Time, error) {
+
+	if l.baseURL != "" {
+		return l.baseURL + key, time.Time{}, nil
+	}
+
+	fp, err := filepath.Abs(l.path(key))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return "file://" + filepath.ToSlash(fp), time.Time{}, nil
+}
+
+func (l *Local) path(key string) string {
+	return filepath.Join(l.dir, filepath.FromSlash(key))
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/pulpfree/univsales-wrksht-pdf/metrics"
+	"github.com/stretchr/testify/suite"
+)
+
+// LocalSuite struct
+type LocalSuite struct {
+	suite.Suite
+	dir string
+	s   *Local
+}
+
+// SetupTest method
+func (suite *LocalSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "wrksht")
+	suite.NoError(err)
+	suite.dir = dir
+	suite.s = NewLocal(dir, "http://localhost/files/")
+}
+
+// TearDownTest method
+func (suite *LocalSuite) TearDownTest() {
+	os.RemoveAll(suite.dir)
+}
+
+// TestPutStat method
+func (suite *LocalSuite) TestPutStat() {
+
+	key := "worksheet/sht-1000.pdf"
+	_, err := suite.s.Stat(key)
+	suite.Equal(ErrNotFound, err)
+
+	put, err := suite.s.Put(key, []byte("pdf"), map[string]string{MetaFingerprint: "abc"})
+	suite.NoError(err)
+	suite.Equal("abc", put.Fingerprint)
+	suite.Equal("http://localhost/files/"+key, put.URL)
+
+	info, err := suite.s.Stat(key)
+	suite.NoError(err)
+	suite.Equal(put.Checksum, info.Checksum)
+	suite.Equal("abc", info.Fingerprint)
+	suite.Equal(int64(3), info.Size)
+}
+
+// TestPutMetrics method
+// Every stored file is measured, whichever caller stores it, except the health probe
+func (suite *LocalSuite) TestPutMetrics() {
+
+	rec := &recorder{}
+	suite.s.metrics = rec
+
+	_, err := suite.s.Put("worksheet/sht-1000/latest.json", []byte("{}"), nil)
+	suite.NoError(err)
+	_, err = suite.s.Put("batch/wrksht-batch.zip", []byte("zip"), nil)
+	suite.NoError(err)
+	// the health probe is not an upload
+	_, err = suite.s.Put(ProbeKey, []byte("ok"), nil)
+	suite.NoError(err)
+
+	suite.Equal([]string{metrics.UploadDuration, metrics.UploadSize, metrics.UploadDuration, metrics.UploadSize}, rec.names)
+	suite.Equal([]float64{2, 3}, rec.sizes)
+}
+
+// ================================ Helper Methods
+
+type recorder struct {
+	names []string
+	sizes []float64
+}
+
+func (r *recorder) Record(name string, value float64, unit metrics.Unit, dims metrics.Dims) {
+	r.names = append(r.names, name)
+	if name == metrics.UploadSize {
+		r.sizes = append(r.sizes, value)
+	}
+}
+
+// TestLocalSuite function
+func TestLocalSuite(t *testing.T) {
+	suite.Run(t, new(LocalSuite))
+}
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/pulpfree/univsales-wrksht-pdf/awsservices"
+	"github.com/pulpfree/univsales-wrksht-pdf/config"
+	"github.com/pulpfree/univsales-wrksht-pdf/metrics"
+)
+
+// S3 struct
+// Stores files as private objects in the configured bucket
+type S3 struct {
+	cfg     *config.Config
+	metrics metrics.Recorder
+}
+
+// NewS3 function
+func NewS3(cfg *config.Config) *S3 {
+	return &S3{cfg: cfg, metrics: metrics.Default()}
+}
+
+// Delete method
+func (s *S3) Delete(keys ...string) error {
+	return awsservices.DeleteObjects(keys, s.cfg)
+}
+
+// Get method
+func (s *S3) Get(key string) ([]byte, error) {
+
+	body, err := awsservices.GetObject(key, s.cfg)
+	if awsservices.IsNotFound(err) {
+		return nil, ErrNotFound
+	}
+
+	return body, err
+}
+
+// List method
+// Object metadata is not included in the listing
+func (s *S3) List(prefix string) ([]*FileInfo, error) {
+
+	objs, err := awsservices.ListObjects(prefix, s.cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]*FileInfo, len(objs))
+	for i, o := range objs {
+		files[i] = &FileInfo{
+			Key:      aws.StringValue(o.Key),
+			Modified: aws.TimeValue(o.LastModified),
+			Size:     aws.Int64Value(o.Size),
+		}
+	}
+
+	return files, nil
+}
+
+// Put method
+// Uploads the file with meta as object metadata and returns a presigned url to download it
+func (s *S3) Put(key string, body []byte, meta map[string]string) (*FileInfo, error) {
+
+	info := newFileInfo(key, body, meta)
+	start := time.Now()
+	err := awsservices.PutObject(key, body, contentType(key), info.Meta, s.cfg)
+	if err != nil {
+		return nil, err
+	}
+	recordPut(s.metrics, key, start, body)
+
+	info.URL, info.Expires, err = s.URL(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// Stat method
+func (s *S3) Stat(key string) (*FileInfo, error) {
+
+	size, meta, err := awsservices.HeadObject(key, s.cfg)
+	if awsservices.IsNotFound(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	info := &FileInfo{Key: key, Size: size}
+	info.setMeta(meta)
+
+	return info, nil
+}
+
+// URL method
+func (s *S3) URL(key string) (string, time.Time, error) {
+	return s.URLFor(key, s.cfg.S3URLExpiry)
+}
+
+// URLFor method
+func (s *S3) URLFor(key string, expiry time.Duration) (string, time.Time, error) {
+	return awsservices.GetSignedURL(key, expiry, s.cfg)
+}
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"path"
+	"time"
+
+	"github.com/pulpfree/univsales-wrksht-pdf/config"
+	"github.com/pulpfree/univsales-wrksht-pdf/metrics"
+)
+
+// content types for the file extensions we store, anything else is stored as a pdf
+var contentTypes = map[string]string{
+	".json": "application/json",
+	".pdf":  "application/pdf",
+	".zip":  "application/zip",
+}
+
+// Metadata keys stored with each file
+const (
+	MetaChecksum    = "Sha256"
+	MetaFingerprint = "Fingerprint"
+	MetaUserEmail   = "User-Email"
+	MetaUserID      = "User-Id"
+)
+
+// ProbeKey is written and read back by the health check, each probe overwrites the last
+const ProbeKey = "health/probe"
+
+// ErrNotFound is returned by Get and Stat when no file is stored under the key
+var ErrNotFound = errors.New("File not found")
+
+// FileInfo struct
+type FileInfo struct {
+	Checksum    string            `json:"checksum"`
+	Expires     time.Time         `json:"expires"`
+	Fingerprint string            `json:"fingerprint,omitempty"`
+	Key         string            `json:"key"`
+	Modified    time.Time         `json:"-"`
+	Meta        map[string]string `json:"-"`
+	Reused      bool              `json:"reused"`
+	Size        int64             `json:"size"`
+	URL         string            `json:"url"`
+}
+
+// Store interface
+type Store interface {
+	Delete(keys ...string) error
+	Get(key string) ([]byte, error)
+	List(prefix string) ([]*FileInfo, error)
+	Put(key string, body []byte, meta map[string]string) (*FileInfo, error)
+	Stat(key string) (*FileInfo, error)
+	URL(key string) (url string, expires time.Time, err error)
+	URLFor(key string, expiry time.Duration) (url string, expires time.Time, err error)
+}
+
+// New function
+// Returns a local directory store when an OutputDir is configured, otherwise the S3 bucket store
+func New(cfg *config.Config) Store {
+	if cfg.OutputDir != "" {
+		return NewLocal(cfg.OutputDir, cfg.OutputURL)
+	}
+	return NewS3(cfg)
+}
+
+// ================================ Helper Functions
+
+// recordPut records the duration and size of a stored file, the health probe is not an upload
+func recordPut(r metrics.Recorder, key string, start time.Time, body []byte) {
+	if key == ProbeKey {
+		return
+	}
+	metrics.Since(r, metrics.UploadDuration, start, nil)
+	r.Record(metrics.UploadSize, float64(len(body)), metrics.Bytes, nil)
+}
+
+// newFileInfo adds the checksum to meta, which is stored with the file
+func newFileInfo(key string, body []byte, meta map[string]string) *FileInfo {
+
+	sum := sha256.Sum256(body)
+	m := map[string]string{}
+	for k, v := range meta {
+		m[k] = v
+	}
+	m[MetaChecksum] = hex.EncodeToString(sum[:])
+
+	info := &FileInfo{Key: key, Size: int64(len(body))}
+	info.setMeta(m)
+
+	return info
+}
+
+func (f *FileInfo) setMeta(meta map[string]string) {
+	f.Meta = meta
+	f.Checksum = meta[MetaChecksum]
+	f.Fingerprint = meta[MetaFingerprint]
+}
+
+func contentType(key string) string {
+	if ct, ok := contentTypes[path.Ext(key)]; ok {
+		return ct
+	}
+	return contentTypes[".pdf"]
+}
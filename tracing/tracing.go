@@ -0,0 +1,129 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/pulpfree/univsales-wrksht-pdf/config"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporter constants, the TraceExporter config values
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+// ServiceName identifies the spans of this service
+const ServiceName = "univsales-wrksht-pdf"
+
+// provider is nil until Configure sets an exporter, spans are then no-ops
+var provider *sdktrace.TracerProvider
+
+// Configure function
+// Sets the global tracer provider to export to the configured exporter. The OTLP endpoint
+// and token are expected from ssm, an http endpoint such as a local collector is sent unencrypted.
+// Called once at start up, before any request is handled
+func Configure(cfg *config.Config) error {
+
+	var (
+		exp sdktrace.SpanExporter
+		err error
+	)
+
+	switch cfg.TraceExporter {
+	case ExporterNone, "":
+		return nil
+	case ExporterStdout:
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		exp, err = otlpExporter(cfg)
+	default:
+		return fmt.Errorf("Invalid TraceExporter value: %s", cfg.TraceExporter)
+	}
+	if err != nil {
+		return err
+	}
+
+	res := resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceNameKey.String(ServiceName),
+		semconv.DeploymentEnvironmentKey.String(string(cfg.GetStageEnv())),
+	)
+	provider = sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+
+	return nil
+}
+
+// Flush function
+// Exports the buffered spans, called at the end of each Lambda invocation as the
+// function may be frozen before the batch is sent
+func Flush(ctx context.Context) {
+	if provider == nil {
+		return
+	}
+	if err := provider.ForceFlush(ctx); err != nil {
+		log.Errorf("Error flushing spans: %s", err)
+	}
+}
+
+// Start function
+// Starts a span as a child of any span in ctx
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(ServiceName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End function
+// Ends the span, marking it failed when err is set
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Context function
+// Returns the context carried by a request logger, so spans started in the mongo, pdf and service
+// layers join the handler span without a context parameter on every method
+func Context(l *log.Entry) context.Context {
+	if l == nil || l.Context == nil {
+		return context.Background()
+	}
+	return l.Context
+}
+
+// ================================ Helper Functions
+
+func otlpExporter(cfg *config.Config) (sdktrace.SpanExporter, error) {
+
+	u, err := url.Parse(cfg.TraceEndpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("Invalid TraceEndpoint value: %s", cfg.TraceEndpoint)
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(u.Host)}
+	if u.Scheme == "http" {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	if u.Path != "" && u.Path != "/" {
+		opts = append(opts, otlptracehttp.WithURLPath(u.Path))
+	}
+	if cfg.TraceToken != "" {
+		opts = append(opts, otlptracehttp.WithHeaders(map[string]string{"Authorization": "Bearer " + cfg.TraceToken}))
+	}
+
+	return otlptracehttp.New(context.Background(), opts...)
+}
@@ -0,0 +1,78 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pulpfree/univsales-wrksht-pdf/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// TracingSuite struct
+type TracingSuite struct {
+	suite.Suite
+	cfg *config.Config
+}
+
+// SetupTest method
+func (suite *TracingSuite) SetupTest() {
+	suite.cfg = &config.Config{}
+	suite.cfg.SetStageEnv("test")
+	provider = nil
+}
+
+// TestConfigure method
+func (suite *TracingSuite) TestConfigure() {
+
+	suite.cfg.TraceExporter = ExporterNone
+	suite.NoError(Configure(suite.cfg))
+	suite.Nil(provider)
+
+	suite.cfg.TraceExporter = "epsagon"
+	suite.Error(Configure(suite.cfg))
+
+	suite.cfg.TraceExporter = ExporterOTLP
+	suite.cfg.TraceEndpoint = "localhost"
+	suite.Error(Configure(suite.cfg))
+
+	suite.cfg.TraceEndpoint = "http://localhost:4318"
+	suite.NoError(Configure(suite.cfg))
+	suite.NotNil(provider)
+}
+
+// TestSpans method
+func (suite *TracingSuite) TestSpans() {
+
+	rec := tracetest.NewSpanRecorder()
+	provider = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	tracer := provider.Tracer(ServiceName)
+
+	ctx, parent := tracer.Start(context.Background(), "POST /")
+	l := log.NewEntry(log.StandardLogger()).WithContext(ctx)
+
+	_, child := tracer.Start(Context(l), "mongo quotes.findOne")
+	End(child, errors.New("timeout"))
+	End(parent, nil)
+
+	spans := rec.Ended()
+	suite.Len(spans, 2)
+	suite.Equal(spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+	suite.Equal("Error", spans[0].Status().Code.String())
+	suite.Len(spans[0].Events(), 1)
+	suite.Equal("Unset", spans[1].Status().Code.String())
+}
+
+// TestContext method
+func (suite *TracingSuite) TestContext() {
+	suite.Equal(context.Background(), Context(nil))
+	suite.Equal(context.Background(), Context(log.NewEntry(log.StandardLogger())))
+}
+
+// TestTracingSuite function
+func TestTracingSuite(t *testing.T) {
+	suite.Run(t, new(TracingSuite))
+}